@@ -0,0 +1,87 @@
+package converter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/phad/msmtohl/model"
+	"github.com/phad/msmtohl/parser/qif"
+)
+
+// qifAccountTypes gives the type of the hledger account for each type of QIF account.
+var qifAccountTypes = map[string]model.AccountType{
+	"Type:Bank":  model.Cash,
+	"Type:Cash":  model.Cash,
+	"Type:CCard": model.Liability,
+	"Type:Invst": model.Asset,
+	"Type:Oth A": model.Asset,
+	"Type:Oth L": model.Liability,
+}
+
+// AccountDeclarations returns a declaration for every account posted to by
+// txns, sorted by name.  Account types are inferred from the QIF account types
+// of sets, then from the income and expense flags of opts.Categories, then
+// from the prefix the account was given.
+func AccountDeclarations(sets []*qif.RecordSet, txns []*model.Transaction, opts *Options) []*model.AccountDeclaration {
+	types := map[string]model.AccountType{}
+	if opts != nil {
+		for _, c := range opts.Categories {
+			if c.Income {
+				types[opts.mapping().Category(c.Name, false)] = model.Revenue
+			}
+			if c.Expense {
+				types[opts.mapping().Category(c.Name, true)] = model.Expense
+			}
+		}
+	}
+	for _, rs := range sets {
+		types[opts.mapping().Account(rs.AccountName())] = qifAccountTypes[rs.Opening.Type]
+	}
+
+	accounts := map[string]model.Account{}
+	var names []string
+	for _, t := range txns {
+		for _, p := range t.Postings {
+			name := strings.Join(p.Account, ":")
+			if _, ok := accounts[name]; !ok {
+				accounts[name] = p.Account
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var decls []*model.AccountDeclaration
+	for _, name := range names {
+		typ, ok := types[name]
+		if !ok {
+			typ = typeFromPrefix(name, opts.mapping())
+		}
+		decls = append(decls, &model.AccountDeclaration{Account: accounts[name], Type: typ})
+	}
+	return decls
+}
+
+// typeFromPrefix infers the type of the named account from the fallback
+// prefixes of m, or hledger's conventional top-level account names.
+func typeFromPrefix(name string, m *Mapping) model.AccountType {
+	for _, p := range []struct {
+		prefix string
+		typ    model.AccountType
+	}{
+		{m.accountPrefix(), model.Asset},
+		{m.expensePrefix(), model.Expense},
+		{m.incomePrefix(), model.Revenue},
+		{"assets:", model.Asset},
+		{"liabilities:", model.Liability},
+		{"equity:", model.Equity},
+		{"income:", model.Revenue},
+		{"revenue:", model.Revenue},
+		{"expenses:", model.Expense},
+	} {
+		if strings.HasPrefix(name, p.prefix) {
+			return p.typ
+		}
+	}
+	return model.UntypedAccount
+}
@@ -0,0 +1,54 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/phad/msmtohl/model"
+	"github.com/phad/msmtohl/parser/qif"
+)
+
+func TestAccountDeclarations(t *testing.T) {
+	opts := &Options{
+		Mapping: &Mapping{Rules: []*Rule{
+			{Exact: "Orange VISA", Account: "liabilities:orange:credit card"},
+			{Exact: "Refunds", Account: "misc:refunds"},
+		}},
+		Categories: map[string]*qif.Category{
+			"Refunds": {Name: "Refunds", Expense: true},
+		},
+	}
+	sets := []*qif.RecordSet{
+		{Opening: &qif.Record{Type: "Type:Bank", Label: "Current"}},
+		{Opening: &qif.Record{Type: "Type:CCard", Label: "Orange VISA"}},
+	}
+	txns := []*model.Transaction{
+		{Postings: []model.Posting{
+			{Account: model.Account{"expenses", "Food"}},
+			{Account: model.Account{"assets", "Current"}},
+		}},
+		{Postings: []model.Posting{
+			{Account: model.Account{"misc", "refunds"}},
+			{Account: model.Account{"income", "Salary"}},
+			{Account: model.Account{"equity", "opening balances"}},
+			{Account: model.Account{"transfer_account"}},
+			{Account: model.Account{"liabilities", "orange", "credit card"}},
+		}},
+	}
+	want := []*model.AccountDeclaration{
+		{Account: model.Account{"assets", "Current"}, Type: model.Cash},
+		{Account: model.Account{"equity", "opening balances"}, Type: model.Equity},
+		{Account: model.Account{"expenses", "Food"}, Type: model.Expense},
+		{Account: model.Account{"income", "Salary"}, Type: model.Revenue},
+		{Account: model.Account{"liabilities", "orange", "credit card"}, Type: model.Liability},
+		{Account: model.Account{"misc", "refunds"}, Type: model.Expense},
+		{Account: model.Account{"transfer_account"}},
+	}
+	got := AccountDeclarations(sets, txns, opts)
+	if !reflect.DeepEqual(got, want) {
+		for i := range got {
+			t.Logf("got %d: %+v", i, got[i])
+		}
+		t.Errorf("AccountDeclarations()=%v want %v", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/phad/msmtohl/model"
+	"github.com/phad/msmtohl/parser/qif"
+)
+
+// Assertions selects which postings to each QIF account are given a balance
+// assertion of the account's running balance.
+type Assertions int
+
+const (
+	// NoAssertions adds no balance assertions.
+	NoAssertions Assertions = iota
+	// EveryTransaction asserts the balance after every transaction.
+	EveryTransaction
+	// MonthEnd asserts the balance after the last transaction of each month.
+	MonthEnd
+)
+
+// ParseAssertions returns the Assertions named by s: "none", "every" or
+// "monthly".
+func ParseAssertions(s string) (Assertions, error) {
+	switch s {
+	case "", "none":
+		return NoAssertions, nil
+	case "every":
+		return EveryTransaction, nil
+	case "monthly":
+		return MonthEnd, nil
+	}
+	return NoAssertions, fmt.Errorf("unknown balance assertions %q", s)
+}
+
+// runningBalance is the balance of an account after one of its postings.
+type runningBalance struct {
+	txn     *model.Transaction
+	posting *model.Posting
+	balance model.Decimal
+}
+
+// AssertBalances tracks the running balance of each QIF account of sets, other
+// than investment accounts, through txns in date order, starting from the
+// account's opening balance transaction.  Postings to the account are given an
+// assertion of that balance as selected by opts.Assertions.  Postings already
+// asserting a balance, such as a statement balance, are left as they are.
+// Every transaction posting to the accounts must be among txns, including
+// transfers from other accounts, so AssertBalances is applied once all the
+// accounts are converted.
+func AssertBalances(sets []*qif.RecordSet, txns []*model.Transaction, opts *Options) {
+	if opts == nil || opts.Assertions == NoAssertions {
+		return
+	}
+	tracked := map[string]bool{}
+	for _, rs := range sets {
+		if rs.Opening.Type != "Type:Invst" {
+			tracked[opts.mapping().Account(rs.AccountName())] = true
+		}
+	}
+
+	sorted := append([]*model.Transaction(nil), txns...)
+	sort.SliceStable(sorted, func(l, r int) bool {
+		return sorted[l].Date.Before(sorted[r].Date)
+	})
+	balances := map[string]model.Decimal{}
+	history := map[string][]runningBalance{}
+	for _, t := range sorted {
+		for i := range t.Postings {
+			p := &t.Postings[i]
+			name := strings.Join(p.Account, ":")
+			if !tracked[name] {
+				continue
+			}
+			balances[name] = balances[name].Add(p.Amount.Quantity)
+			history[name] = append(history[name], runningBalance{txn: t, posting: p, balance: balances[name]})
+		}
+	}
+
+	for _, h := range history {
+		for i, b := range h {
+			if b.posting.Assertion != nil {
+				continue
+			}
+			if opts.Assertions == MonthEnd && i+1 < len(h) && sameMonth(h[i+1].txn, b.txn) {
+				continue
+			}
+			b.posting.Assertion = &model.Amount{Quantity: b.balance, Commodity: b.posting.Amount.Commodity}
+		}
+	}
+}
+
+// sameMonth reports whether the transactions t and u are dated in the same
+// month.
+func sameMonth(t, u *model.Transaction) bool {
+	return t.Date.Year() == u.Date.Year() && t.Date.Month() == u.Date.Month()
+}
@@ -0,0 +1,124 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/phad/msmtohl/model"
+	"github.com/phad/msmtohl/parser/qif"
+)
+
+func TestParseAssertions(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Assertions
+		wantErr bool
+	}{
+		{s: "", want: NoAssertions},
+		{s: "none", want: NoAssertions},
+		{s: "every", want: EveryTransaction},
+		{s: "monthly", want: MonthEnd},
+		{s: "daily", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseAssertions(test.s)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("ParseAssertions(%q)=_, err? %t want? %t (err=%v)", test.s, gotErr, test.wantErr, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseAssertions(%q)=%v want %v", test.s, got, test.want)
+		}
+	}
+}
+
+func TestAssertBalances(t *testing.T) {
+	sets := []*qif.RecordSet{
+		{Opening: &qif.Record{Type: "Type:Bank", Label: "Current"}},
+		{Opening: &qif.Record{Type: "Type:Bank", Label: "Savings"}},
+		{Opening: &qif.Record{Type: "Type:Invst", Label: "Shares"}},
+	}
+	gbp := model.NewCommodity("£")
+	posting := func(account string, amount string) model.Posting {
+		return model.Posting{Account: toAccount(account), Amount: model.Amount{Quantity: model.MustParseDecimal(amount), Commodity: gbp}}
+	}
+	txn := func(date string, postings ...model.Posting) *model.Transaction {
+		d, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &model.Transaction{Date: d, Postings: postings}
+	}
+	statement := &model.Amount{Quantity: model.MustParseDecimal("999.00"), Commodity: gbp}
+	// newTxns returns the transactions, listed out of date order, with the
+	// balance of each account posted to, in order, after each.
+	newTxns := func() ([]*model.Transaction, [][]string) {
+		txns := []*model.Transaction{
+			txn("2016-01-01", posting("equity:opening balances", "-100"), posting("assets:Current", "100")),
+			txn("2016-01-20", posting("expenses:Food", "20.50"), posting("assets:Current", "-20.50")),
+			txn("2016-01-10", posting("assets:Savings", "50.00"), posting("assets:Current", "-50.00")),
+			txn("2016-02-01", posting("assets:Shares", "10"), posting("assets:Current", "-10.00")),
+			txn("2016-02-03", posting("income:Interest", "-1.00"), posting("assets:Savings", "1.00")),
+			txn("2016-02-03", posting("income:Interest", "-2.00"), posting("assets:Savings", "2.00")),
+		}
+		txns[5].Postings[1].Assertion = statement
+		balances := [][]string{
+			{"", "100"},
+			{"", "29.50"},
+			{"50.00", "50.00"},
+			{"", "19.50"},
+			{"", "51.00"},
+			{"", "53.00"},
+		}
+		return txns, balances
+	}
+
+	tests := []struct {
+		desc   string
+		opts   *Options
+		assert map[[2]int]bool // The postings, by transaction and index, asserted.
+	}{
+		{
+			desc: "nil options",
+		},
+		{
+			desc: "none",
+			opts: &Options{Assertions: NoAssertions},
+		},
+		{
+			desc: "every transaction",
+			opts: &Options{Assertions: EveryTransaction},
+			assert: map[[2]int]bool{
+				{0, 1}: true, {1, 1}: true, {2, 0}: true, {2, 1}: true, {3, 1}: true, {4, 1}: true,
+			},
+		},
+		{
+			desc: "month end",
+			opts: &Options{Assertions: MonthEnd},
+			assert: map[[2]int]bool{
+				{1, 1}: true, {2, 0}: true, {3, 1}: true,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			txns, balances := newTxns()
+			AssertBalances(sets, txns, test.opts)
+			for i, txn := range txns {
+				for j, p := range txn.Postings {
+					var want *model.Amount
+					switch {
+					case i == 5 && j == 1:
+						want = statement
+					case test.assert[[2]int{i, j}]:
+						want = &model.Amount{Quantity: model.MustParseDecimal(balances[i][j]), Commodity: gbp}
+					}
+					if !reflect.DeepEqual(p.Assertion, want) {
+						t.Errorf("transaction %d posting %d assertion=%v want %v", i, j, p.Assertion, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -3,65 +3,257 @@ package converter
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
-
-	"github.com/golang/glog"
+	"time"
 
 	"github.com/phad/msmtohl/model"
 	"github.com/phad/msmtohl/parser/qif"
 )
 
+// openingBalancesAccount is the account that opening balances are taken from.
+const openingBalancesAccount = "equity:opening balances"
+
+// Options controls how QIF records are converted.  A nil *Options selects the
+// defaults for every setting.
+type Options struct {
+	Mapping   *Mapping // Maps QIF account and category names to hledger accounts.
+	Commodity string   // Default commodity symbol for accounts the Mapping gives none.
+
+	// Categories from QIF category lists, by name.  Their income and expense
+	// flags decide whether a category is filed under income or expenses;
+	// categories not listed are filed by the sign of their amount.
+	Categories map[string]*qif.Category
+
+	// Assertions selects the postings AssertBalances gives balance assertions.
+	Assertions Assertions
+
+	// Dates is the dialect that the dates of QIF records are written in.
+	Dates qif.DateDialect
+
+	// Amounts is the format that the amounts of QIF records are written in.
+	Amounts qif.AmountFormat
+
+	// MethodTags is whether to tag transactions with the payment method
+	// their QIF number identifies, eg. method:direct-debit for DD.
+	MethodTags bool
+}
+
+func (o *Options) mapping() *Mapping {
+	if o == nil {
+		return nil
+	}
+	return o.Mapping
+}
+
+// isExpense reports whether the QIF category given is an expense category.
+// A subcategory not listed in o.Categories takes its parent's flags.  Failing
+// that, negative amounts are taken to be expenses.
+func (o *Options) isExpense(category string, amount model.Decimal) bool {
+	for name := category; o != nil && name != ""; {
+		if c, ok := o.Categories[name]; ok && (c.Income || c.Expense) {
+			return c.Expense
+		}
+		i := strings.LastIndex(name, ":")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return amount.Sign() < 0
+}
+
+// parseDate parses a QIF date written in the dialect o.Dates.
+func (o *Options) parseDate(d string) (time.Time, error) {
+	if o == nil {
+		return qif.ParseDate(d)
+	}
+	return o.Dates.Parse(d)
+}
+
+// parseAmount parses a QIF amount written in the format o.Amounts, treating
+// an empty one as zero.
+func (o *Options) parseAmount(a string) (model.Decimal, error) {
+	var af qif.AmountFormat
+	if o != nil {
+		af = o.Amounts
+	}
+	n, err := af.Normalize(a)
+	if err != nil || strings.TrimSpace(n) == "" {
+		return model.Decimal{}, err
+	}
+	return model.ParseDecimal(n)
+}
+
+// paymentMethods gives the payment method for the identifiers that Money and
+// UK banks use in place of a cheque number.
+var paymentMethods = map[string]string{
+	"ATM": "cash-machine",
+	"BGC": "bank-giro-credit",
+	"CHQ": "cheque",
+	"DD":  "direct-debit",
+	"DEP": "deposit",
+	"EFT": "electronic-transfer",
+	"POS": "card",
+	"SO":  "standing-order",
+	"TFR": "transfer",
+}
+
+// methodTags returns a method: tag for the payment method identified by the
+// QIF number n, if opts.MethodTags is set.  Numbers made only of digits are
+// taken to be cheque numbers, so n must not be a bank's transaction ID.
+func (o *Options) methodTags(n string) []model.Tag {
+	if o == nil || !o.MethodTags {
+		return nil
+	}
+	n = strings.ToUpper(strings.TrimSpace(n))
+	m, ok := paymentMethods[n]
+	if !ok && n != "" && strings.Trim(n, "0123456789") == "" {
+		m, ok = "cheque", true
+	}
+	if !ok {
+		return nil
+	}
+	return []model.Tag{{Name: "method", Value: m}}
+}
+
+// commodity returns the Commodity that amounts in the named QIF account are in.
+func (o *Options) commodity(account string) model.Commodity {
+	if sym, ok := o.mapping().Commodity(account); ok {
+		return model.NewCommodity(sym)
+	}
+	if o == nil {
+		return model.Commodity{}
+	}
+	return model.NewCommodity(o.Commodity)
+}
+
+// RecordError reports a QIF record that couldn't be converted.
+type RecordError struct {
+	Account string // The name of the account the record is of.
+	Index   int    // The index of the record in the account's Records, or -1 for its opening record.
+	Record  *qif.Record
+	Err     error
+}
+
+// Error conforms with error for RecordErrors.
+func (e *RecordError) Error() string {
+	rec := fmt.Sprintf("record %d", e.Index)
+	if e.Index < 0 {
+		rec = "opening record"
+	}
+	return fmt.Sprintf("account %q %s (%v): %v", e.Account, rec, e.Record, e.Err)
+}
+
+// Unwrap returns the cause of e.
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
 // FromQIF converts the QIF RecordSet provided into a set of Transactions.
-func FromQIF(rs *qif.RecordSet) ([]*model.Transaction, error) {
+// Errors in converting a record are returned as *RecordErrors.
+func FromQIF(rs *qif.RecordSet, opts *Options) ([]*model.Transaction, error) {
 	var txns []*model.Transaction
-	fromPosting, err := fromOpening(rs.Opening)
+	fromPosting, err := fromOpening(rs, opts)
 	if err != nil {
 		return nil, err
 	}
-	for _, r := range rs.Records {
-		t, err := fromQIFRecord(r, fromPosting)
+	opening, err := openingBalance(rs.Opening, fromPosting, opts)
+	if err != nil {
+		return nil, &RecordError{Account: rs.AccountName(), Index: -1, Record: rs.Opening, Err: err}
+	}
+	if opening != nil {
+		txns = append(txns, opening)
+	}
+	convert := fromQIFRecord
+	if rs.Opening.Type == "Type:Invst" {
+		convert = fromInvstRecord
+	}
+	for i, r := range rs.Records {
+		t, err := convert(r, fromPosting, opts)
 		if err != nil {
-			glog.Errorf("Converting from QIF %v error: %v", r, err)
-			return nil, err
+			return nil, &RecordError{Account: rs.AccountName(), Index: i, Record: r, Err: err}
 		}
 		txns = append(txns, t)
 	}
+	if rs.Opening.Type != "Type:Invst" {
+		if err := statementBalance(rs.Account, txns, fromPosting, opts); err != nil {
+			return nil, err
+		}
+	}
 	return txns, nil
 }
 
-func fromQIFRecord(r *qif.Record, fromPosting *model.Posting) (*model.Transaction, error) {
-	d, err := qif.ParseDate(r.Date)
+// statementBalance asserts the statement balance of the account a, if it has
+// one, on the posting to the account of the last of txns dated no later than
+// the statement.
+func statementBalance(a *qif.Account, txns []*model.Transaction, fromPosting *model.Posting, opts *Options) error {
+	if a == nil || a.Balance == "" || a.BalanceDate == "" {
+		return nil
+	}
+	d, err := opts.parseDate(a.BalanceDate)
+	if err != nil {
+		return fmt.Errorf("account %q statement date: %w", a.Name, err)
+	}
+	bal, err := opts.parseAmount(a.Balance)
+	if err != nil {
+		return fmt.Errorf("account %q statement balance: %w", a.Name, err)
+	}
+	var last *model.Transaction
+	for _, t := range txns {
+		if !t.Date.After(d) && (last == nil || !t.Date.Before(last.Date)) {
+			last = t
+		}
+	}
+	if last != nil {
+		last.Postings[len(last.Postings)-1].Assertion = &model.Amount{Quantity: bal, Commodity: fromPosting.Amount.Commodity}
+	}
+	return nil
+}
+
+func fromQIFRecord(r *qif.Record, fromPosting *model.Posting, opts *Options) (*model.Transaction, error) {
+	d, err := opts.parseDate(r.Date)
 	if err != nil {
 		return nil, err
 	}
 	txn := &model.Transaction{
 		Date:        d,
 		Status:      fromQIFStatus(r.Cleared),
+		Code:        code(r),
 		Payee:       r.Payee,
 		Description: r.Memo,
+		Tags:        opts.methodTags(r.Number),
 	}
 	if len(r.Splits) > 0 {
-		for _, s := range r.Splits {
-			isExpense := strings.HasPrefix(s.Amount, "-")
-			s.Category = reformatCategory(s.Category, isExpense)
-			p, err := fromSplit(s)
-			if err != nil {
-				return nil, err
+		amounts, err := splitAmounts(r, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i, s := range r.Splits {
+			category := opts.mapping().Category(s.Category, opts.isExpense(s.Category, amounts[i]))
+			if s.Transfer {
+				category = opts.mapping().Account(s.Category)
 			}
-			txn.Postings = append(txn.Postings, *p)
+			txn.Postings = append(txn.Postings, model.Posting{
+				Account: toAccount(category),
+				Amount:  model.Amount{Quantity: amounts[i].Neg(), Commodity: fromPosting.Amount.Commodity},
+				Comment: s.Memo,
+				Tags:    classTags(s.Class),
+			})
 		}
-		txn.Postings = append(txn.Postings, *fromPosting)
+		txn.Postings = append(txn.Postings, balancing(txn.Postings, fromPosting))
 		return txn, nil
 	}
 	// Regular, unsplit transaction.  This can include inter-account transfers,
-	// if we find one we fix up to mention transfer_account.
+	// which post directly to the other account.
 	var p *model.Posting
-	isExpense := strings.HasPrefix(r.Amount, "-")
-	category := reformatCategory(r.Label, isExpense)
+	amount, err := opts.parseAmount(r.Amount)
+	if err != nil {
+		return nil, err
+	}
+	category := opts.mapping().Category(r.Label, opts.isExpense(r.Label, amount))
 	if r.Transfer {
-		category = "transfer_account"
-		if isExpense {
+		category = opts.mapping().Account(r.Label)
+		if amount.Sign() < 0 {
 			txn.Comment = fmt.Sprintf("transfer-to:%q", r.Label)
 		} else {
 			txn.Comment = fmt.Sprintf("transfer-from:%q", r.Label)
@@ -70,14 +262,82 @@ func fromQIFRecord(r *qif.Record, fromPosting *model.Posting) (*model.Transactio
 	p, err = fromSplit(&qif.Split{
 		Amount:   r.Amount,
 		Category: category,
-	})
+	}, fromPosting.Amount.Commodity, opts)
 	if err != nil {
 		return nil, err
 	}
-	txn.Postings = append(txn.Postings, []model.Posting{*p, *fromPosting}...)
+	p.Tags = classTags(r.Class)
+	txn.Postings = append(txn.Postings, *p)
+	txn.Postings = append(txn.Postings, balancing(txn.Postings, fromPosting))
 	return txn, err
 }
 
+// code returns the transaction code of r: its ID if it has one, such as the
+// FITID of an OFX transaction, otherwise its QIF number.
+func code(r *qif.Record) string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return strings.TrimSpace(r.Number)
+}
+
+// classTags returns a class: tag for the QIF class given, if any.
+func classTags(class string) []model.Tag {
+	if class == "" {
+		return nil
+	}
+	return []model.Tag{{Name: "class", Value: class}}
+}
+
+// splitAmounts returns the amount of each of r's Splits.  Splits given as a
+// percentage, written in the format opts.Amounts with or without a % sign,
+// are resolved against r.Amount and rounded, halves away from zero,
+// to its decimal places.  If the percentages add up to 100 the last of those
+// splits takes whatever remains, so that the splits add up to r.Amount exactly.
+func splitAmounts(r *qif.Record, opts *Options) ([]model.Decimal, error) {
+	total, err := opts.parseAmount(r.Amount)
+	if err != nil {
+		return nil, err
+	}
+	amounts := make([]model.Decimal, len(r.Splits))
+	var sum, pctSum model.Decimal
+	last := -1
+	for i, s := range r.Splits {
+		if s.Percent == "" || s.Amount != "" {
+			if amounts[i], err = opts.parseAmount(s.Amount); err != nil {
+				return nil, err
+			}
+			sum = sum.Add(amounts[i])
+			continue
+		}
+		pct, err := opts.parseAmount(strings.TrimSuffix(strings.TrimSpace(s.Percent), "%"))
+		if err != nil {
+			return nil, fmt.Errorf("split %d percentage: %v", i, err)
+		}
+		amounts[i] = total.Percent(pct).Round(total.Scale())
+		sum = sum.Add(amounts[i])
+		pctSum = pctSum.Add(pct)
+		last = i
+	}
+	if last >= 0 && pctSum.Equal(model.NewDecimal(100, 0)) {
+		amounts[last] = total.Sub(sum.Sub(amounts[last]))
+	}
+	return amounts, nil
+}
+
+// balancing returns a copy of the Posting p with its Amount set to balance the
+// given postings exactly.  Postings with a Cost are balanced at that cost,
+// which is assumed to be in p's commodity.
+func balancing(postings []model.Posting, p *model.Posting) model.Posting {
+	var sum model.Decimal
+	for _, o := range postings {
+		sum = sum.Add(o.Weight().Quantity)
+	}
+	b := *p
+	b.Amount.Quantity = sum.Neg()
+	return b
+}
+
 func fromQIFStatus(qs string) model.Status {
 	switch qs {
 	case " ":
@@ -90,89 +350,52 @@ func fromQIFStatus(qs string) model.Status {
 	return model.Unknown
 }
 
-func fromOpening(op *qif.Record) (*model.Posting, error) {
-	return fromSplit(&qif.Split{Category: reformatCategory(op.Label, false), Amount: "0"})
+func fromOpening(rs *qif.RecordSet, opts *Options) (*model.Posting, error) {
+	name := rs.AccountName()
+	return fromSplit(&qif.Split{Category: opts.mapping().Account(name), Amount: "0"}, opts.commodity(name), opts)
 }
 
-func fromSplit(s *qif.Split) (*model.Posting, error) {
-	amount, err := strconv.ParseFloat(sanitizeAmount(s.Amount), 64)
+// openingBalance returns a Transaction that sets the starting balance of the
+// account to the amount of its opening record op, on the date of that record,
+// against the openingBalancesAccount.  It returns nil if op has no date, which
+// is the case for opening records not read from a QIF file.
+func openingBalance(op *qif.Record, fromPosting *model.Posting, opts *Options) (*model.Transaction, error) {
+	if op.Date == "" {
+		return nil, nil
+	}
+	d, err := opts.parseDate(op.Date)
 	if err != nil {
 		return nil, err
 	}
-	var ac model.Account
-	if s.Category == "" {
-		ac = append(ac, "((unknown account))")
-	} else {
-		for _, s := range strings.Split(s.Category, ":") {
-			ac = append(ac, s)
-		}
+	amount := op.Amount
+	if amount == "" {
+		amount = "0"
+	}
+	p, err := fromSplit(&qif.Split{Category: openingBalancesAccount, Amount: amount}, fromPosting.Amount.Commodity, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Transaction{
+		Date:        d,
+		Status:      fromQIFStatus(op.Cleared),
+		Payee:       op.Payee,
+		Description: op.Memo,
+		Postings:    []model.Posting{*p, balancing([]model.Posting{*p}, fromPosting)},
+	}, nil
+}
+
+func fromSplit(s *qif.Split, c model.Commodity, opts *Options) (*model.Posting, error) {
+	amount, err := opts.parseAmount(s.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Posting{Amount: model.Amount{Quantity: amount.Neg(), Commodity: c}, Account: toAccount(s.Category)}, nil
+}
+
+// toAccount splits a colon-separated account name into an Account.
+func toAccount(name string) model.Account {
+	if name == "" {
+		return model.Account{"((unknown account))"}
 	}
-	// glog.Infof("fromSplit: account=%q", ac)
-	return &model.Posting{Amount: -amount, Account: ac}, nil
-}
-
-func sanitizeAmount(a string) string {
-	// Remove , 1000s separator
-	return strings.Replace(a, ",", "", -1)
-}
-
-func reformatCategory(c string, isExpense bool) string {
-	switch c {
-	case "Abbey ex-TESSA":
-		return "assets:bank:abbey national:paul:ex-tessa"
-	case "Joint - smile Current":
-		return "assets:bank:smile:joint:current"
-	case "Joint - smile Savings":
-		return "assets:bank:smile:joint:savings"
-	case "Joint - smile Savings 2 (house)":
-		return "assets:bank:smile:joint:savings 2 (house)"
-	case "Miranda - Halifax  Savings":
-		return "assets:bank:halifax:miranda:savings"
-	case "Orange VISA":
-		return "liabilities:bank:orange:paul:credit card"
-	case "Oscar - goHenry":
-		return "assets:bank:gohenry:oscar"
-	case "Oscar - Halifax Ch Regular Saver":
-		return "assets:bank:halifax:oscar:ch regular saver"
-	case "Oscar - Halifax Save4It":
-			return "assets:bank:halifax:oscar:save4it"
-	case "Paul - Barclays Current":
-		return "assets:bank:barclays:paul:current"
-	case "Paul - Cahoot Credit Card":
-		return "liabilities:bank:cahoot:paul:credit card"
-	case "Paul - Cahoot Current":
-		return "assets:bank:cahoot:paul:current"
-	case "Paul - Cahoot Savings":
-		return "assets:bank:cahoot:paul:savings"
-	case "Paul - Halifax Savings":
-			return "assets:bank:halifax:paul:savings"
-	case "Paul - Monese Current":
-		return "assets:bank:monese:paul:current"
-	case "Paul - Monese Prepay - Old":
-		return "assets:bank:monese:paul:prepay - old"
-	case "Paul - Monzo Current":
-		return "assets:bank:monzo:paul:current"
-	case "Paul - Monzo Mastercard":
-		return "assets:bank:monzo:paul:mastercard"
-	case "Paul - smile cash mini-ISA":
-		return "assets:bank:smile:paul:cash mini-isa"
-	case "Paul - smile Current":
-		return "assets:bank:smile:paul:current"
-	case "Rachel - Barclays Savings":
-		return "assets:bank:barclays:rachel:savings"
-	case "Rachel - HSBC Current":
-		return "assets:bank:hsbc:rachel:current"
-	case "Rachel - HSBC Savings":
-		return "assets:bank:hsbc:rachel:savings"
-	case "Rachel - Monzo Cureent":
-		return "assets:bank:monzo:rachel:current"
-	case "Rachel - Smile Current":
-		return "assets:bank:smile:rachel:current"
-	case "Rachel - Smile Mini ISA":
-		return "assets:bank:smile:rachel:mini_-isa"
-	}
-	if isExpense {
-		return "expenses:"+c
-	}
-	return "income:"+c
+	return strings.Split(name, ":")
 }
@@ -1,43 +1,49 @@
 package converter
 
 import (
+	"errors"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/phad/msmtohl/model"
+	"github.com/phad/msmtohl/parser/ofx"
 	"github.com/phad/msmtohl/parser/qif"
 )
 
 func TestFromQIFRecord(t *testing.T) {
-	tests := []struct{
-		desc string
-		qifRec *qif.Record
+	tests := []struct {
+		desc    string
+		qifRec  *qif.Record
 		opening *model.Posting
-		want *model.Transaction
+		opts    *Options
+		want    *model.Transaction
 		wantErr bool
 	}{
 		{
 			desc: "mangled date",
 			qifRec: &qif.Record{
-				Date: "12'02/2016",
+				Date:    "12'02/2016",
 				Cleared: "C",
-				Payee: "Dave",
-				Memo: "New shoes",
+				Payee:   "Dave",
+				Memo:    "New shoes",
 			},
 			opening: &model.Posting{},
 			wantErr: true,
 		},
 		{
-			desc: "record with no splits",
+			desc: "record with no splits, class tagged",
 			qifRec: &qif.Record{
-				Date: "12/02'2016",
+				Date:    "12/02'2016",
 				Cleared: "C",
-				Payee: "Dave",
-				Amount: "-123",
-				Label: "Clothes:Shoes",
-				Memo: "New shoes",
-				Splits: []*qif.Split{},
+				Payee:   "Dave",
+				Amount:  "-123",
+				Label:   "Clothes:Shoes",
+				Class:   "Holiday2019",
+				Memo:    "New shoes",
+				Splits:  []*qif.Split{},
 			},
 			opening: &model.Posting{
 				Account: []string{"smile", "current"},
@@ -47,9 +53,158 @@ func TestFromQIFRecord(t *testing.T) {
 				Status:      model.Pending,
 				Payee:       "Dave",
 				Description: "New shoes",
-				Postings:    []model.Posting{
-					{Amount: 123.0, Account: []string{"expenses", "Clothes", "Shoes"}},
-					{Account: []string{"smile", "current"}},
+				Postings: []model.Posting{
+					{Amount: model.Amount{Quantity: model.NewDecimal(123, 0)}, Account: []string{"expenses", "Clothes", "Shoes"}, Tags: []model.Tag{{Name: "class", Value: "Holiday2019"}}},
+					{Amount: model.Amount{Quantity: model.NewDecimal(-123, 0)}, Account: []string{"smile", "current"}},
+				},
+			},
+		},
+		{
+			desc: "number becomes the code",
+			qifRec: &qif.Record{
+				Date:   "12/02'2016",
+				Number: "001234 ",
+				Amount: "-5",
+				Label:  "Food",
+			},
+			opening: &model.Posting{
+				Account: []string{"smile", "current"},
+			},
+			want: &model.Transaction{
+				Date: time.Date(2016, time.February, 12, 0, 0, 0, 0, time.UTC),
+				Code: "001234",
+				Postings: []model.Posting{
+					{Amount: model.Amount{Quantity: model.NewDecimal(5, 0)}, Account: []string{"expenses", "Food"}},
+					{Amount: model.Amount{Quantity: model.NewDecimal(-5, 0)}, Account: []string{"smile", "current"}},
+				},
+			},
+		},
+		{
+			desc: "ID becomes the code",
+			qifRec: &qif.Record{
+				Date:   "12/02'2016",
+				ID:     "201602120001",
+				Amount: "-5",
+				Label:  "Food",
+			},
+			opening: &model.Posting{
+				Account: []string{"smile", "current"},
+			},
+			want: &model.Transaction{
+				Date: time.Date(2016, time.February, 12, 0, 0, 0, 0, time.UTC),
+				Code: "201602120001",
+				Postings: []model.Posting{
+					{Amount: model.Amount{Quantity: model.NewDecimal(5, 0)}, Account: []string{"expenses", "Food"}},
+					{Amount: model.Amount{Quantity: model.NewDecimal(-5, 0)}, Account: []string{"smile", "current"}},
+				},
+			},
+		},
+		{
+			desc: "number tagged with payment method",
+			qifRec: &qif.Record{
+				Date:   "12/02'2016",
+				Number: "dd",
+				Amount: "-5",
+				Label:  "Bills",
+			},
+			opening: &model.Posting{
+				Account: []string{"smile", "current"},
+			},
+			opts: &Options{MethodTags: true},
+			want: &model.Transaction{
+				Date: time.Date(2016, time.February, 12, 0, 0, 0, 0, time.UTC),
+				Code: "dd",
+				Tags: []model.Tag{{Name: "method", Value: "direct-debit"}},
+				Postings: []model.Posting{
+					{Amount: model.Amount{Quantity: model.NewDecimal(5, 0)}, Account: []string{"expenses", "Bills"}},
+					{Amount: model.Amount{Quantity: model.NewDecimal(-5, 0)}, Account: []string{"smile", "current"}},
+				},
+			},
+		},
+		{
+			desc: "numeric ID not tagged as a cheque",
+			qifRec: &qif.Record{
+				Date:   "12/02'2016",
+				ID:     "201602120001",
+				Amount: "-5",
+				Label:  "Food",
+			},
+			opening: &model.Posting{
+				Account: []string{"smile", "current"},
+			},
+			opts: &Options{MethodTags: true},
+			want: &model.Transaction{
+				Date: time.Date(2016, time.February, 12, 0, 0, 0, 0, time.UTC),
+				Code: "201602120001",
+				Postings: []model.Posting{
+					{Amount: model.Amount{Quantity: model.NewDecimal(5, 0)}, Account: []string{"expenses", "Food"}},
+					{Amount: model.Amount{Quantity: model.NewDecimal(-5, 0)}, Account: []string{"smile", "current"}},
+				},
+			},
+		},
+		{
+			desc: "transfer posts to the other account",
+			qifRec: &qif.Record{
+				Date:     "12/02'2016",
+				Amount:   "-100.00",
+				Label:    "Savings",
+				Transfer: true,
+			},
+			opening: &model.Posting{
+				Account: []string{"smile", "current"},
+			},
+			want: &model.Transaction{
+				Date:    time.Date(2016, time.February, 12, 0, 0, 0, 0, time.UTC),
+				Comment: `transfer-to:"Savings"`,
+				Postings: []model.Posting{
+					{Amount: model.Amount{Quantity: model.MustParseDecimal("100.00")}, Account: []string{"assets", "Savings"}},
+					{Amount: model.Amount{Quantity: model.MustParseDecimal("-100.00")}, Account: []string{"smile", "current"}},
+				},
+			},
+		},
+		{
+			desc: "split transfer posts to the other account",
+			qifRec: &qif.Record{
+				Date:   "12/02'2016",
+				Amount: "-105.00",
+				Splits: []*qif.Split{
+					{Category: "Savings", Transfer: true, Amount: "-100.00"},
+					{Category: "Bank Charges", Amount: "-5.00"},
+				},
+			},
+			opening: &model.Posting{
+				Account: []string{"smile", "current"},
+			},
+			want: &model.Transaction{
+				Date: time.Date(2016, time.February, 12, 0, 0, 0, 0, time.UTC),
+				Postings: []model.Posting{
+					{Amount: model.Amount{Quantity: model.MustParseDecimal("100.00")}, Account: []string{"assets", "Savings"}},
+					{Amount: model.Amount{Quantity: model.MustParseDecimal("5.00")}, Account: []string{"expenses", "Bank Charges"}},
+					{Amount: model.Amount{Quantity: model.MustParseDecimal("-105.00")}, Account: []string{"smile", "current"}},
+				},
+			},
+		},
+		{
+			desc: "record with splits balances exactly, split memos and classes kept",
+			qifRec: &qif.Record{
+				Date:   "12/02'2016",
+				Amount: "-0.30",
+				Splits: []*qif.Split{
+					{Category: "Food", Amount: "-0.10", Memo: "Bread"},
+					{Category: "Food", Class: "Work:Travel", Amount: "-0.10"},
+					{Category: "Food", Amount: "-0.1"},
+				},
+			},
+			opening: &model.Posting{
+				Account: []string{"smile", "current"},
+			},
+			want: &model.Transaction{
+				Date: time.Date(2016, time.February, 12, 0, 0, 0, 0, time.UTC),
+				Postings: []model.Posting{
+					{Amount: model.Amount{Quantity: model.MustParseDecimal("0.10")}, Account: []string{"expenses", "Food"}, Comment: "Bread"},
+					{Amount: model.Amount{Quantity: model.MustParseDecimal("0.10")}, Account: []string{"expenses", "Food"}, Tags: []model.Tag{{Name: "class", Value: "Work:Travel"}}},
+					{Amount: model.Amount{Quantity: model.MustParseDecimal("0.1")}, Account: []string{"expenses", "Food"}},
+					{Amount: model.Amount{Quantity: model.MustParseDecimal("-0.30")}, Account: []string{"smile", "current"}},
 				},
 			},
 		},
@@ -57,7 +212,7 @@ func TestFromQIFRecord(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.desc, func(t *testing.T) {
-			txn, err := fromQIFRecord(test.qifRec, test.opening)
+			txn, err := fromQIFRecord(test.qifRec, test.opening, test.opts)
 			if gotErr := err != nil; gotErr != test.wantErr {
 				t.Errorf("fromQIFRecord()=_, err? %t want? %t (err=%v)", gotErr, test.wantErr, err)
 			}
@@ -68,26 +223,295 @@ func TestFromQIFRecord(t *testing.T) {
 	}
 }
 
+func TestFromQIF_commodities(t *testing.T) {
+	rs := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:Bank", Label: "Euro Account", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "01/02'2016", Amount: "-100.00", Label: "Travel"},
+		},
+	}
+	tests := []struct {
+		desc string
+		opts *Options
+		want model.Commodity
+	}{
+		{desc: "no commodity by default"},
+		{
+			desc: "default commodity",
+			opts: &Options{Commodity: "£"},
+			want: model.Commodity{Symbol: "£"},
+		},
+		{
+			desc: "per-account commodity overrides default",
+			opts: &Options{
+				Commodity: "£",
+				Mapping:   &Mapping{Commodities: map[string]string{"Euro Account": "EUR"}},
+			},
+			want: model.Commodity{Symbol: "EUR", Suffix: true, Spaced: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			txns, err := FromQIF(rs, test.opts)
+			if err != nil {
+				t.Fatalf("FromQIF() error: %v", err)
+			}
+			for _, p := range txns[0].Postings {
+				if got := p.Amount.Commodity; got != test.want {
+					t.Errorf("FromQIF() posting %v commodity=%+v want %+v", p.Account, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFromQIF_opening(t *testing.T) {
+	rs := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:CCard", Date: "01/01'2016", Amount: "-1,234.56", Cleared: "X", Payee: "Opening Balance", Label: "VISA", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "02/01'2016", Amount: "-10.00", Label: "Travel"},
+		},
+	}
+	txns, err := FromQIF(rs, &Options{Commodity: "£"})
+	if err != nil {
+		t.Fatalf("FromQIF() error: %v", err)
+	}
+	if got, want := len(txns), 2; got != want {
+		t.Fatalf("FromQIF() got %d transactions want %d", got, want)
+	}
+	pound := model.Commodity{Symbol: "£"}
+	want := &model.Transaction{
+		Date:   time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Status: model.Cleared,
+		Payee:  "Opening Balance",
+		Postings: []model.Posting{
+			{Account: model.Account{"equity", "opening balances"}, Amount: model.Amount{Quantity: model.MustParseDecimal("1234.56"), Commodity: pound}},
+			{Account: model.Account{"assets", "VISA"}, Amount: model.Amount{Quantity: model.MustParseDecimal("-1234.56"), Commodity: pound}},
+		},
+	}
+	if got := txns[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("FromQIF() opening=%+v want %+v", got, want)
+	}
+}
+
+func TestFromQIF_statementBalance(t *testing.T) {
+	rs := &qif.RecordSet{
+		Account: &qif.Account{Name: "12345678", Type: "Bank", Balance: "1,224.06", BalanceDate: "31/01'2017"},
+		Opening: &qif.Record{Type: "Type:Bank", Label: "12345678", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "12/01'2017", Amount: "-10.50"},
+			{Date: "31/01'2017", Amount: "1234.56"},
+			{Date: "20/01'2017", Amount: "-1.00"},
+			{Date: "01/02'2017", Amount: "-2.00"},
+		},
+	}
+	txns, err := FromQIF(rs, &Options{Commodity: "£"})
+	if err != nil {
+		t.Fatalf("FromQIF() error: %v", err)
+	}
+	want := &model.Amount{Quantity: model.MustParseDecimal("1224.06"), Commodity: model.Commodity{Symbol: "£"}}
+	for i, txn := range txns {
+		var w *model.Amount
+		if i == 1 {
+			w = want
+		}
+		for j, p := range txn.Postings {
+			if j < len(txn.Postings)-1 && p.Assertion != nil {
+				t.Errorf("FromQIF() transaction %d posting %d has assertion %v", i, j, p.Assertion)
+			}
+		}
+		if got := txn.Postings[len(txn.Postings)-1].Assertion; !reflect.DeepEqual(got, w) {
+			t.Errorf("FromQIF() transaction %d assertion=%v want %v", i, got, w)
+		}
+	}
+}
+
+func TestFromQIF_ofxStatementBalance(t *testing.T) {
+	const statement = `<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKACCTFROM><ACCTID>12345678</ACCTID></BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20170120</DTPOSTED><TRNAMT>5.00</TRNAMT><FITID>2</FITID></STMTTRN>
+<STMTTRN><DTPOSTED>20170202</DTPOSTED><TRNAMT>-3.00</TRNAMT><FITID>3</FITID></STMTTRN>
+<STMTTRN><DTPOSTED>20170112</DTPOSTED><TRNAMT>-10.50</TRNAMT><FITID>1</FITID></STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>994.50</BALAMT><DTASOF>20170131</DTASOF></LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+	f, err := ofx.ReadFile(strings.NewReader(statement), nil)
+	if err != nil {
+		t.Fatalf("ofx.ReadFile() error: %v", err)
+	}
+	txns, err := FromQIF(f.Accounts[0], &Options{Commodity: "£"})
+	if err != nil {
+		t.Fatalf("FromQIF() error: %v", err)
+	}
+	sort.SliceStable(txns, func(i, j int) bool { return txns[i].Date.Before(txns[j].Date) })
+	account := model.Account{"assets", "12345678"}
+	var balance model.Decimal
+	assertions := 0
+	for _, txn := range txns {
+		for _, p := range txn.Postings {
+			if !reflect.DeepEqual(p.Account, account) {
+				continue
+			}
+			balance = balance.Add(p.Amount.Quantity)
+			if p.Assertion != nil {
+				assertions++
+				if !balance.Equal(p.Assertion.Quantity) {
+					t.Errorf("FromQIF() %s balance=%s fails assertion %s", txn.Date.Format("2006-01-02"), balance, p.Assertion.Quantity)
+				}
+			}
+		}
+	}
+	if assertions != 1 {
+		t.Errorf("FromQIF() got %d assertions want 1", assertions)
+	}
+	if got, want := txns[0].Date, time.Date(2017, time.January, 11, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("FromQIF() opening date=%v want %v", got, want)
+	}
+}
+
+func TestFromQIF_statementBalanceError(t *testing.T) {
+	tests := []struct {
+		account *qif.Account
+		cause   error
+	}{
+		{account: &qif.Account{Name: "VISA", Type: "CCard", Balance: "-1.00", BalanceDate: "31/02'2017"}, cause: qif.ErrMalformedDate},
+		{account: &qif.Account{Name: "VISA", Type: "CCard", Balance: "-1,00", BalanceDate: "31/01'2017"}, cause: qif.ErrMalformedAmount},
+	}
+	for _, test := range tests {
+		rs := &qif.RecordSet{
+			Account: test.account,
+			Opening: &qif.Record{Type: "Type:CCard", Label: "VISA", Transfer: true},
+		}
+		if _, err := FromQIF(rs, nil); !errors.Is(err, test.cause) {
+			t.Errorf("FromQIF(%+v)=_, %v want cause %v", test.account, err, test.cause)
+		}
+	}
+}
+
+func TestSplitAmounts(t *testing.T) {
+	tests := []struct {
+		desc    string
+		amount  string
+		splits  []*qif.Split
+		opts    *Options
+		want    []string
+		wantErr bool
+	}{
+		{
+			desc:   "amounts only",
+			amount: "-14.40",
+			splits: []*qif.Split{{Amount: "-10.00"}, {Amount: "-4.40"}},
+			want:   []string{"-10.00", "-4.40"},
+		},
+		{
+			desc:   "percentages resolve exactly",
+			amount: "-10.00",
+			splits: []*qif.Split{{Percent: "33"}, {Percent: "33"}, {Percent: "34%"}},
+			want:   []string{"-3.30", "-3.30", "-3.40"},
+		},
+		{
+			desc:   "rounding remainder goes to last percentage split",
+			amount: "-10.01",
+			splits: []*qif.Split{{Percent: "50.00"}, {Percent: "50.00"}},
+			want:   []string{"-5.01", "-5.00"},
+		},
+		{
+			desc:   "thirds",
+			amount: "100.00",
+			splits: []*qif.Split{{Percent: "33.33"}, {Percent: "33.33"}, {Percent: "33.34"}},
+			want:   []string{"33.33", "33.33", "33.34"},
+		},
+		{
+			desc:   "percentages not adding up to 100 are only rounded",
+			amount: "-10.01",
+			splits: []*qif.Split{{Amount: "-5.00"}, {Percent: "25"}},
+			want:   []string{"-5.00", "-2.50"},
+		},
+		{
+			desc:   "amount preferred to percentage",
+			amount: "-10.00",
+			splits: []*qif.Split{{Amount: "-2.00", Percent: "20"}, {Percent: "80"}},
+			want:   []string{"-2.00", "-8.00"},
+		},
+		{
+			desc:   "percentages in the amount format",
+			amount: "-10,00",
+			splits: []*qif.Split{{Percent: "50,5%"}, {Percent: "49,5"}},
+			opts:   &Options{Amounts: qif.AmountFormat{DecimalMark: ','}},
+			want:   []string{"-5.05", "-4.95"},
+		},
+		{
+			desc:    "bad percentage",
+			amount:  "-10.00",
+			splits:  []*qif.Split{{Percent: "half"}},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			amounts, err := splitAmounts(&qif.Record{Amount: test.amount, Splits: test.splits}, test.opts)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Errorf("splitAmounts()=_, err? %t want? %t (err=%v)", gotErr, test.wantErr, err)
+			}
+			var got []string
+			for _, a := range amounts {
+				got = append(got, a.String())
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("splitAmounts()=%q want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFromQIFRecord_categoryFlags(t *testing.T) {
+	opts := &Options{Categories: map[string]*qif.Category{
+		"Clothes": {Name: "Clothes", Expense: true},
+		"Salary":  {Name: "Salary", Income: true},
+	}}
+	opening := &model.Posting{Account: []string{"smile", "current"}}
+	tests := []struct {
+		label, amount string
+		want          model.Account
+	}{
+		{"Clothes:Shoes", "-10.00", model.Account{"expenses", "Clothes", "Shoes"}},
+		{"Clothes:Shoes", "10.00", model.Account{"expenses", "Clothes", "Shoes"}},
+		{"Salary", "-10.00", model.Account{"income", "Salary"}},
+		{"Unlisted", "10.00", model.Account{"income", "Unlisted"}},
+		{"Unlisted", "-10.00", model.Account{"expenses", "Unlisted"}},
+	}
+	for _, test := range tests {
+		txn, err := fromQIFRecord(&qif.Record{Date: "12/02'2016", Amount: test.amount, Label: test.label}, opening, opts)
+		if err != nil {
+			t.Fatalf("fromQIFRecord() error: %v", err)
+		}
+		if got := txn.Postings[0].Account; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("fromQIFRecord(%s %s) account=%v want %v", test.label, test.amount, got, test.want)
+		}
+	}
+}
+
 func TestFromQIFStatus(t *testing.T) {
-	tests := []struct{
+	tests := []struct {
 		inputs []string
-		want model.Status
+		want   model.Status
 	}{
 		{
 			inputs: []string{"", "A", "blah"},
-			want: model.Unknown,
+			want:   model.Unknown,
 		},
 		{
 			inputs: []string{" "},
-			want: model.Unmarked,
+			want:   model.Unmarked,
 		},
 		{
 			inputs: []string{"*", "C"},
-			want: model.Pending,
+			want:   model.Pending,
 		},
 		{
 			inputs: []string{"R", "X"},
-			want: model.Cleared,
+			want:   model.Cleared,
 		},
 	}
 	for _, test := range tests {
@@ -100,37 +524,49 @@ func TestFromQIFStatus(t *testing.T) {
 }
 
 func TestFromSplit(t *testing.T) {
-	tests := []struct{
-		desc string
-		split *qif.Split
-		want *model.Posting
+	tests := []struct {
+		desc    string
+		split   *qif.Split
+		opts    *Options
+		want    *model.Posting
 		wantErr bool
 	}{
 		{
-			desc: "-ve amount, simple category",
+			desc:  "-ve amount, simple category",
 			split: &qif.Split{Amount: "-35.00", Category: "foo"},
-			want: &model.Posting{Amount: 35.0, Account: []string{"foo"}},
+			want:  &model.Posting{Amount: model.Amount{Quantity: model.MustParseDecimal("35.00")}, Account: []string{"foo"}},
 		},
 		{
-			desc: "-ve amount, empty category",
+			desc:  "-ve amount, empty category",
 			split: &qif.Split{Amount: "-12.34", Category: ""},
-			want: &model.Posting{Amount: 12.34, Account: []string{"((unknown account))"}},
+			want:  &model.Posting{Amount: model.Amount{Quantity: model.MustParseDecimal("12.34")}, Account: []string{"((unknown account))"}},
 		},
 		{
-			desc: "+ve amount, compound category",
+			desc:  "+ve amount, compound category",
 			split: &qif.Split{Amount: "56.89", Category: "foo:bar:baz"},
-			want: &model.Posting{Amount: -56.89, Account: []string{"foo", "bar", "baz"}},
+			want:  &model.Posting{Amount: model.Amount{Quantity: model.MustParseDecimal("-56.89")}, Account: []string{"foo", "bar", "baz"}},
 		},
 		{
-			desc: "thousands separators removed",
+			desc:  "thousands separators removed",
 			split: &qif.Split{Amount: "1,234,567.89", Category: "big"},
-			want: &model.Posting{Amount: -1234567.89, Account: []string{"big"}},
+			want:  &model.Posting{Amount: model.Amount{Quantity: model.MustParseDecimal("-1234567.89")}, Account: []string{"big"}},
+		},
+		{
+			desc:  "European marks",
+			split: &qif.Split{Amount: "-1.234,56 €", Category: "foo"},
+			opts:  &Options{Amounts: qif.AmountFormat{DecimalMark: ','}},
+			want:  &model.Posting{Amount: model.Amount{Quantity: model.MustParseDecimal("1234.56")}, Account: []string{"foo"}},
+		},
+		{
+			desc:    "ambiguous grouping mark",
+			split:   &qif.Split{Amount: "12,34", Category: "foo"},
+			wantErr: true,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.desc, func(t *testing.T) {
-			posting, err := fromSplit(test.split)
+			posting, err := fromSplit(test.split, model.Commodity{}, test.opts)
 			if gotErr := err != nil; gotErr != test.wantErr {
 				t.Errorf("fromSplit()=_, err? %t want? %t (err=%v)", gotErr, test.wantErr, err)
 			}
@@ -140,3 +576,95 @@ func TestFromSplit(t *testing.T) {
 		})
 	}
 }
+
+func TestMethodTags(t *testing.T) {
+	tests := []struct {
+		opts   *Options
+		number string
+		want   []model.Tag
+	}{
+		{opts: nil, number: "DD"},
+		{opts: &Options{}, number: "DD"},
+		{opts: &Options{MethodTags: true}, number: ""},
+		{opts: &Options{MethodTags: true}, number: "Print"},
+		{opts: &Options{MethodTags: true}, number: "SO", want: []model.Tag{{Name: "method", Value: "standing-order"}}},
+		{opts: &Options{MethodTags: true}, number: " atm", want: []model.Tag{{Name: "method", Value: "cash-machine"}}},
+		{opts: &Options{MethodTags: true}, number: "001234", want: []model.Tag{{Name: "method", Value: "cheque"}}},
+	}
+	for _, test := range tests {
+		if got := test.opts.methodTags(test.number); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%+v methodTags(%q)=%v want %v", test.opts, test.number, got, test.want)
+		}
+	}
+}
+
+func TestFromQIF_dates(t *testing.T) {
+	rs := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:Bank", Date: "1/ 2'98", Amount: "10.00", Label: "Current", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "12/31'98", Amount: "-1.00", Label: "Food"},
+		},
+	}
+	txns, err := FromQIF(rs, &Options{Dates: qif.DateDialect{Order: qif.MonthDay}})
+	if err != nil {
+		t.Fatalf("FromQIF() error: %v", err)
+	}
+	want := []time.Time{
+		time.Date(1998, time.January, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(1998, time.December, 31, 0, 0, 0, 0, time.UTC),
+	}
+	for i, txn := range txns {
+		if !txn.Date.Equal(want[i]) {
+			t.Errorf("FromQIF() transaction %d date=%v want %v", i, txn.Date, want[i])
+		}
+	}
+	if _, err := FromQIF(rs, nil); err == nil {
+		t.Errorf("FromQIF() with day-first dates got no error")
+	}
+}
+
+func TestFromQIF_recordError(t *testing.T) {
+	opening := &qif.Record{Type: "Type:Bank", Date: "01/01'2017", Amount: "10.00", Label: "Current", Transfer: true}
+	tests := []struct {
+		desc      string
+		opening   *qif.Record
+		records   []*qif.Record
+		wantIndex int
+		cause     error
+	}{
+		{
+			desc:      "bad date",
+			opening:   opening,
+			records:   []*qif.Record{{Date: "02/01'2017", Amount: "-1.00"}, {Date: "31/02'2017", Amount: "-1.00"}},
+			wantIndex: 1,
+			cause:     qif.ErrMalformedDate,
+		},
+		{
+			desc:      "bad amount",
+			opening:   opening,
+			records:   []*qif.Record{{Date: "02/01'2017", Amount: "-1,00"}},
+			wantIndex: 0,
+			cause:     qif.ErrMalformedAmount,
+		},
+		{
+			desc:      "bad opening amount",
+			opening:   &qif.Record{Type: "Type:Bank", Date: "01/01'2017", Amount: "1.2.3", Label: "Current", Transfer: true},
+			wantIndex: -1,
+			cause:     qif.ErrMalformedAmount,
+		},
+	}
+	for _, test := range tests {
+		_, err := FromQIF(&qif.RecordSet{Opening: test.opening, Records: test.records}, nil)
+		var re *RecordError
+		if !errors.As(err, &re) {
+			t.Errorf("%s: FromQIF()=_, %v want a *RecordError", test.desc, err)
+			continue
+		}
+		if re.Account != "Current" || re.Index != test.wantIndex {
+			t.Errorf("%s: FromQIF() error account %q index %d want %q index %d", test.desc, re.Account, re.Index, "Current", test.wantIndex)
+		}
+		if !errors.Is(err, test.cause) {
+			t.Errorf("%s: FromQIF()=_, %v want cause %v", test.desc, err, test.cause)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package converter
+
+import (
+	"github.com/phad/msmtohl/parser/qif"
+)
+
+// RemoveDuplicates removes from sets every record with the same ID as an
+// earlier record of the same account, as when OFX statements downloaded for
+// overlapping periods are converted together.  Records with no ID are kept.
+// Of the dated opening balances of an account's RecordSets, only the earliest
+// is kept; the others are cleared of their date and amount, since each
+// statement's opening balance already includes the transactions of those
+// before it; their dates are read in the default dialect, as written by
+// qif.NormalizeDates.  It returns the number of records removed.
+func RemoveDuplicates(sets []*qif.RecordSet) int {
+	seen := map[string]map[string]bool{}
+	openings := map[string]*qif.Record{}
+	removed := 0
+	for _, rs := range sets {
+		name := rs.AccountName()
+		if seen[name] == nil {
+			seen[name] = map[string]bool{}
+		}
+		keepEarliest(openings, name, rs.Opening)
+		ids := seen[name]
+		kept := rs.Records[:0]
+		for _, r := range rs.Records {
+			if r.ID != "" && ids[r.ID] {
+				removed++
+				continue
+			}
+			if r.ID != "" {
+				ids[r.ID] = true
+			}
+			kept = append(kept, r)
+		}
+		rs.Records = kept
+	}
+	return removed
+}
+
+// keepEarliest clears the date and amount of whichever of op, the opening
+// record of the named account, and the one already in openings is the later,
+// leaving the earlier in openings.  Openings with no date, or one that can't be
+// read, are left alone.
+func keepEarliest(openings map[string]*qif.Record, name string, op *qif.Record) {
+	d, err := qif.ParseDate(op.Date)
+	if err != nil {
+		return
+	}
+	prev := openings[name]
+	if prev == nil {
+		openings[name] = op
+		return
+	}
+	if pd, _ := qif.ParseDate(prev.Date); d.Before(pd) {
+		openings[name], op = op, prev
+	}
+	op.Date, op.Amount = "", ""
+}
@@ -0,0 +1,50 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/phad/msmtohl/parser/qif"
+)
+
+func TestRemoveDuplicates(t *testing.T) {
+	jan := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:Bank", Date: "11/01'2017", Amount: "100.00", Label: "12345678", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "12/01'2017", Amount: "-10.50", ID: "A1"},
+			{Date: "31/01'2017", Amount: "-5.00", ID: "A2"},
+			{Date: "31/01'2017", Amount: "-1.00"},
+		},
+	}
+	feb := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:Bank", Date: "30/01'2017", Amount: "89.50", Label: "12345678", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "31/01'2017", Amount: "-5.00", ID: "A2"},
+			{Date: "31/01'2017", Amount: "-1.00"},
+			{Date: "01/02'2017", Amount: "-2.00", ID: "A3"},
+		},
+	}
+	other := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:CCard", Date: "11/01'2017", Amount: "-5.00", Label: "87654321", Transfer: true},
+		Records: []*qif.Record{{Date: "12/01'2017", Amount: "-10.50", ID: "A1"}},
+	}
+	if got, want := RemoveDuplicates([]*qif.RecordSet{feb, jan, other}), 1; got != want {
+		t.Errorf("RemoveDuplicates()=%d want %d", got, want)
+	}
+	var ids []string
+	for _, rs := range []*qif.RecordSet{feb, jan, other} {
+		for _, r := range rs.Records {
+			ids = append(ids, r.ID)
+		}
+	}
+	if want := []string{"A2", "", "A3", "A1", "", "A1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("RemoveDuplicates() left IDs %q want %q", ids, want)
+	}
+	var openings []string
+	for _, rs := range []*qif.RecordSet{jan, feb, other} {
+		openings = append(openings, rs.Opening.Date+" "+rs.Opening.Amount)
+	}
+	if want := []string{"11/01'2017 100.00", " ", "11/01'2017 -5.00"}; !reflect.DeepEqual(openings, want) {
+		t.Errorf("RemoveDuplicates() left openings %q want %q", openings, want)
+	}
+}
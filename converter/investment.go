@@ -0,0 +1,266 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/phad/msmtohl/model"
+	"github.com/phad/msmtohl/parser/qif"
+)
+
+// Categories that investment records are filed under when they give none.
+const (
+	commissionCategory   = "Investment:Commission"
+	dividendCategory     = "Investment:Dividends"
+	interestCategory     = "Investment:Interest"
+	capitalGainsCategory = "Investment:Capital Gains"
+	miscIncomeCategory   = "Investment:Other Income"
+	miscExpenseCategory  = "Investment:Other Expenses"
+
+	// shareTransferAccount is the other side of shares added or removed with
+	// no cash changing hands, unless the record names a transfer account.
+	shareTransferAccount = "equity:share transfers"
+)
+
+// incomeCategories gives the default category for actions that pay income,
+// either as cash or reinvested in the security.
+var incomeCategories = map[string]string{
+	"Div":      dividendCategory,
+	"ReinvDiv": dividendCategory,
+	"IntInc":   interestCategory,
+	"ReinvInt": interestCategory,
+	"CGLong":   capitalGainsCategory,
+	"CGMid":    capitalGainsCategory,
+	"CGShort":  capitalGainsCategory,
+	"ReinvLg":  capitalGainsCategory,
+	"ReinvMd":  capitalGainsCategory,
+	"ReinvSh":  capitalGainsCategory,
+	"MiscInc":  miscIncomeCategory,
+}
+
+// ErrUnsupportedAction is the cause of the error converting an investment
+// record whose action isn't one that this package converts, such as StkSplit.
+var ErrUnsupportedAction = errors.New("unsupported investment action")
+
+// actionKind is how the records of an investment action are converted.
+type actionKind int
+
+const (
+	sharesAction   actionKind = iota + 1 // Adds or removes shares.
+	incomeAction                         // Pays cash income.
+	expenseAction                        // Pays cash expenses.
+	transferAction                       // Moves cash to or from another account.
+	cashAction                           // Is a regular, bank account like, record.
+)
+
+// actionKinds gives the kind of each investment action supported, without the
+// X suffix of actions whose cash goes to another account.
+var actionKinds = map[string]actionKind{
+	"Buy": sharesAction, "Sell": sharesAction, "ShrsIn": sharesAction, "ShrsOut": sharesAction,
+	"ReinvDiv": sharesAction, "ReinvInt": sharesAction, "ReinvLg": sharesAction, "ReinvMd": sharesAction, "ReinvSh": sharesAction,
+	"Div": incomeAction, "IntInc": incomeAction, "CGLong": incomeAction, "CGMid": incomeAction, "CGShort": incomeAction, "MiscInc": incomeAction,
+	"MiscExp": expenseAction, "MargInt": expenseAction,
+	"XIn": transferAction, "XOut": transferAction,
+	"Cash": cashAction,
+}
+
+// splitAction returns the investment action a without any X suffix, and
+// whether it had one.
+func splitAction(a string) (string, bool) {
+	if len(a) > 1 && strings.HasSuffix(a, "X") {
+		return strings.TrimSuffix(a, "X"), true
+	}
+	return a, false
+}
+
+// RemoveUnsupported removes from the investment accounts of sets every record
+// whose action isn't one that FromQIF converts, returning a *RecordError, with
+// the cause ErrUnsupportedAction, for each.
+func RemoveUnsupported(sets []*qif.RecordSet) []*RecordError {
+	var removed []*RecordError
+	for _, rs := range sets {
+		if rs.Opening.Type != "Type:Invst" {
+			continue
+		}
+		var kept []*qif.Record
+		for i, r := range rs.Records {
+			if action, _ := splitAction(r.Action); actionKinds[action] == 0 {
+				removed = append(removed, &RecordError{Account: rs.AccountName(), Index: i, Record: r, Err: fmt.Errorf("%w %q", ErrUnsupportedAction, r.Action)})
+				continue
+			}
+			kept = append(kept, r)
+		}
+		rs.Records = kept
+	}
+	return removed
+}
+
+// fromInvstRecord converts a record from a Type:Invst account.  Shares are
+// held in the investment account itself, in a commodity named after the
+// security, and cash postings are made to the same account unless the action
+// ends in X, in which case they go to the transfer account in r.Label.
+func fromInvstRecord(r *qif.Record, cash *model.Posting, opts *Options) (*model.Transaction, error) {
+	d, err := opts.parseDate(r.Date)
+	if err != nil {
+		return nil, err
+	}
+	txn := &model.Transaction{
+		Date:        d,
+		Status:      fromQIFStatus(r.Cleared),
+		Payee:       r.Payee,
+		Description: r.Memo,
+	}
+	if txn.Payee == "" {
+		txn.Payee = r.Security
+	}
+	cashLeg := *cash
+	action, x := splitAction(r.Action)
+	if x {
+		cashLeg.Account = toAccount(opts.mapping().Account(r.Label))
+	}
+	var (
+		postings []model.Posting
+		last     model.Posting
+	)
+	switch actionKinds[action] {
+	case sharesAction:
+		postings, last, err = sharesPostings(r, action, cash.Account, &cashLeg, opts)
+	case incomeAction:
+		postings, err = categoryPostings(r, incomeCategories[action], false, cash.Amount.Commodity, opts)
+		last = cashLeg
+	case expenseAction:
+		postings, err = categoryPostings(r, miscExpenseCategory, true, cash.Amount.Commodity, opts)
+		last = cashLeg
+	case transferAction:
+		postings, err = transferPostings(r, action == "XOut", cash.Amount.Commodity, opts)
+		last = cashLeg
+	case cashAction:
+		return fromQIFRecord(r, cash, opts)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedAction, r.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	txn.Postings = append(postings, balancing(postings, &last))
+	return txn, nil
+}
+
+// sharesPostings returns the postings for an action that adds shares to or
+// removes them from the holding account, along with the posting that balances
+// them: the cash leg for Buy and Sell, the income category for reinvestments,
+// and the transfer account for ShrsIn and ShrsOut.
+func sharesPostings(r *qif.Record, action string, holding model.Account, cashLeg *model.Posting, opts *Options) ([]model.Posting, model.Posting, error) {
+	sign := model.NewDecimal(1, 0)
+	if action == "Sell" || action == "ShrsOut" {
+		sign = sign.Neg()
+	}
+	commission, err := opts.parseAmount(r.Commission)
+	if err != nil {
+		return nil, model.Posting{}, err
+	}
+	shares, err := sharesPosting(r, sign, commission, holding, cashLeg.Amount.Commodity, opts)
+	if err != nil {
+		return nil, model.Posting{}, err
+	}
+	postings := []model.Posting{shares}
+	if !commission.IsZero() {
+		postings = append(postings, model.Posting{
+			Account: toAccount(opts.mapping().Category(commissionCategory, true)),
+			Amount:  model.Amount{Quantity: commission, Commodity: cashLeg.Amount.Commodity},
+		})
+	}
+	last := *cashLeg
+	if shares.Cost == nil {
+		// Shares of unknown cost can only be balanced by shares.
+		last.Amount.Commodity = shares.Amount.Commodity
+	}
+	switch {
+	case strings.HasPrefix(action, "Reinv"):
+		last.Account = toAccount(opts.mapping().Category(incomeCategories[action], false))
+	case action == "ShrsIn" || action == "ShrsOut":
+		last.Account = toAccount(shareTransferAccount)
+		if r.Transfer {
+			last.Account = toAccount(opts.mapping().Account(r.Label))
+		}
+	}
+	return postings, last, nil
+}
+
+// sharesPosting returns the posting of r.Quantity shares, multiplied by sign,
+// in the security r.Security.  Its cost, in commodity c, is the unit price
+// r.Price if that accounts exactly for the total amount r.Amount less any
+// commission, otherwise the total cost.
+func sharesPosting(r *qif.Record, sign, commission model.Decimal, holding model.Account, c model.Commodity, opts *Options) (model.Posting, error) {
+	qty, err := opts.parseAmount(r.Quantity)
+	if err != nil {
+		return model.Posting{}, err
+	}
+	price, err := opts.parseAmount(r.Price)
+	if err != nil {
+		return model.Posting{}, err
+	}
+	total, err := opts.parseAmount(r.Amount)
+	if err != nil {
+		return model.Posting{}, err
+	}
+	p := model.Posting{
+		Account: holding,
+		Amount:  model.Amount{Quantity: qty.Abs().Mul(sign), Commodity: model.NewCommodity(r.Security)},
+	}
+	// Commission is paid on top of the cost of shares bought, and out of the
+	// proceeds of shares sold.
+	cost := total.Abs().Sub(commission.Mul(sign))
+	switch {
+	case r.Price != "" && (r.Amount == "" || qty.Abs().Mul(price).Equal(cost)):
+		p.Cost = &model.Amount{Quantity: price, Commodity: c}
+	case r.Amount != "":
+		p.Cost = &model.Amount{Quantity: cost, Commodity: c}
+		p.TotalCost = true
+	}
+	return p, nil
+}
+
+// categoryPostings returns the posting of r.Amount to r's category, or the
+// default category given if r has none.  Income is credited to the category
+// and expenses debited.
+func categoryPostings(r *qif.Record, category string, isExpense bool, c model.Commodity, opts *Options) ([]model.Posting, error) {
+	amount, err := opts.parseAmount(r.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if r.Label != "" && !r.Transfer {
+		category = r.Label
+	}
+	amount = amount.Abs()
+	if !isExpense {
+		amount = amount.Neg()
+	}
+	return []model.Posting{{
+		Account: toAccount(opts.mapping().Category(category, isExpense)),
+		Amount:  model.Amount{Quantity: amount, Commodity: c},
+	}}, nil
+}
+
+// transferPostings returns the posting of cash to (out is true) or from the
+// transfer account named in r.Label.
+func transferPostings(r *qif.Record, out bool, c model.Commodity, opts *Options) ([]model.Posting, error) {
+	amount, err := opts.parseAmount(r.TransferAmount)
+	if err != nil {
+		return nil, err
+	}
+	if r.TransferAmount == "" {
+		if amount, err = opts.parseAmount(r.Amount); err != nil {
+			return nil, err
+		}
+	}
+	amount = amount.Abs()
+	if !out {
+		amount = amount.Neg()
+	}
+	return []model.Posting{{
+		Account: toAccount(opts.mapping().Account(r.Label)),
+		Amount:  model.Amount{Quantity: amount, Commodity: c},
+	}}, nil
+}
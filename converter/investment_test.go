@@ -0,0 +1,162 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/phad/msmtohl/model"
+	"github.com/phad/msmtohl/parser/qif"
+)
+
+func TestFromInvstRecord(t *testing.T) {
+	gbp := model.NewCommodity("£")
+	vwrl := model.NewCommodity("VWRL")
+	isa := model.Account{"assets", "ISA"}
+	cash := &model.Posting{Account: isa, Amount: model.Amount{Commodity: gbp}}
+	d := time.Date(2003, time.March, 15, 0, 0, 0, 0, time.UTC)
+	amount := func(s string, c model.Commodity) model.Amount {
+		return model.Amount{Quantity: model.MustParseDecimal(s), Commodity: c}
+	}
+	cost := func(s string) *model.Amount {
+		a := amount(s, gbp)
+		return &a
+	}
+
+	tests := []struct {
+		desc    string
+		qifRec  *qif.Record
+		want    []model.Posting
+		wantErr bool
+	}{
+		{
+			desc:    "unsupported action",
+			qifRec:  &qif.Record{Date: "15/03'2003", Action: "StkSplit"},
+			wantErr: true,
+		},
+		{
+			desc:    "bad quantity",
+			qifRec:  &qif.Record{Date: "15/03'2003", Action: "Buy", Security: "VWRL", Quantity: "ten"},
+			wantErr: true,
+		},
+		{
+			desc:   "buy at unit price with commission",
+			qifRec: &qif.Record{Date: "15/03'2003", Action: "Buy", Security: "VWRL", Price: "50.00", Quantity: "10", Commission: "5.00", Amount: "505.00"},
+			want: []model.Posting{
+				{Account: isa, Amount: amount("10", vwrl), Cost: cost("50.00")},
+				{Account: model.Account{"expenses", "Investment", "Commission"}, Amount: amount("5.00", gbp)},
+				{Account: isa, Amount: amount("-505.00", gbp)},
+			},
+		},
+		{
+			desc:   "buy with rounded unit price uses total cost",
+			qifRec: &qif.Record{Date: "15/03'2003", Action: "Buy", Security: "VWRL", Price: "33.3333", Quantity: "3", Amount: "100.00"},
+			want: []model.Posting{
+				{Account: isa, Amount: amount("3", vwrl), Cost: cost("100.00"), TotalCost: true},
+				{Account: isa, Amount: amount("-100.00", gbp)},
+			},
+		},
+		{
+			desc:   "sell to transfer account",
+			qifRec: &qif.Record{Date: "15/03'2003", Action: "SellX", Security: "VWRL", Price: "60.00", Quantity: "10", Commission: "5.00", Amount: "595.00", Label: "Current", Transfer: true},
+			want: []model.Posting{
+				{Account: isa, Amount: amount("-10", vwrl), Cost: cost("60.00")},
+				{Account: model.Account{"expenses", "Investment", "Commission"}, Amount: amount("5.00", gbp)},
+				{Account: model.Account{"assets", "Current"}, Amount: amount("595.00", gbp)},
+			},
+		},
+		{
+			desc:   "reinvested dividend",
+			qifRec: &qif.Record{Date: "15/03'2003", Action: "ReinvDiv", Security: "VWRL", Price: "50.00", Quantity: "0.5", Amount: "25.00"},
+			want: []model.Posting{
+				{Account: isa, Amount: amount("0.5", vwrl), Cost: cost("50.00")},
+				{Account: model.Account{"income", "Investment", "Dividends"}, Amount: amount("-25.00", gbp)},
+			},
+		},
+		{
+			desc:   "shares transferred in",
+			qifRec: &qif.Record{Date: "15/03'2003", Action: "ShrsIn", Security: "VWRL", Quantity: "10"},
+			want: []model.Posting{
+				{Account: isa, Amount: amount("10", vwrl)},
+				{Account: model.Account{"equity", "share transfers"}, Amount: amount("-10", vwrl)},
+			},
+		},
+		{
+			desc:   "cash dividend",
+			qifRec: &qif.Record{Date: "15/03'2003", Action: "Div", Security: "VWRL", Amount: "12.34"},
+			want: []model.Posting{
+				{Account: model.Account{"income", "Investment", "Dividends"}, Amount: amount("-12.34", gbp)},
+				{Account: isa, Amount: amount("12.34", gbp)},
+			},
+		},
+		{
+			desc:   "cash transferred out",
+			qifRec: &qif.Record{Date: "15/03'2003", Action: "XOut", Amount: "100.00", TransferAmount: "100.00", Label: "Current", Transfer: true},
+			want: []model.Posting{
+				{Account: model.Account{"assets", "Current"}, Amount: amount("100.00", gbp)},
+				{Account: isa, Amount: amount("-100.00", gbp)},
+			},
+		},
+		{
+			desc:   "cash action is a regular record",
+			qifRec: &qif.Record{Date: "15/03'2003", Action: "Cash", Amount: "-3.00", Label: "Bank Charges"},
+			want: []model.Posting{
+				{Account: model.Account{"expenses", "Bank Charges"}, Amount: amount("3.00", gbp)},
+				{Account: isa, Amount: amount("-3.00", gbp)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			txn, err := fromInvstRecord(test.qifRec, cash, nil)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("fromInvstRecord()=_, err? %t want? %t (err=%v)", gotErr, test.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if txn.Date != d {
+				t.Errorf("fromInvstRecord().Date=%v want %v", txn.Date, d)
+			}
+			if !reflect.DeepEqual(txn.Postings, test.want) {
+				t.Errorf("fromInvstRecord().Postings=%+v want %+v", txn.Postings, test.want)
+			}
+		})
+	}
+}
+
+func TestRemoveUnsupported(t *testing.T) {
+	isa := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:Invst", Label: "ISA", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "15/03'2003", Action: "Buy", Security: "VWRL", Quantity: "10", Amount: "500.00"},
+			{Date: "16/03'2003", Action: "StkSplit", Security: "VWRL", Quantity: "2"},
+			{Date: "17/03'2003", Action: "RtrnCapX", Security: "VWRL", Amount: "5.00", Label: "Current", Transfer: true},
+			{Date: "18/03'2003", Action: "SellX", Security: "VWRL", Quantity: "10", Amount: "600.00", Label: "Current", Transfer: true},
+		},
+	}
+	bank := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:Bank", Label: "Current", Transfer: true},
+		Records: []*qif.Record{{Date: "15/03'2003", Amount: "-1.00"}},
+	}
+	removed := RemoveUnsupported([]*qif.RecordSet{isa, bank})
+	var got []string
+	for _, e := range removed {
+		if !errors.Is(e, ErrUnsupportedAction) {
+			t.Errorf("RemoveUnsupported() error %v want cause %v", e, ErrUnsupportedAction)
+		}
+		got = append(got, fmt.Sprintf("%s %d %s", e.Account, e.Index, e.Record.Action))
+	}
+	if want := []string{"ISA 1 StkSplit", "ISA 2 RtrnCapX"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RemoveUnsupported() removed %q want %q", got, want)
+	}
+	if got, want := len(isa.Records), 2; got != want {
+		t.Errorf("RemoveUnsupported() left %d investment records want %d", got, want)
+	}
+	if got, want := len(bank.Records), 1; got != want {
+		t.Errorf("RemoveUnsupported() left %d bank records want %d", got, want)
+	}
+}
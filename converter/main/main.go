@@ -1,45 +1,242 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/phad/msmtohl/converter"
 	"github.com/phad/msmtohl/model"
+	"github.com/phad/msmtohl/parser/csv"
+	"github.com/phad/msmtohl/parser/ofx"
 	"github.com/phad/msmtohl/parser/qif"
-	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding"
 )
 
 var (
-	inFiles = flag.String("in_files", "", "Comma-separated input files in QIF format.")
-	outFile = flag.String("out_file", "", "Output file in hledger format.")
+	inFiles = flag.String("in_files", "", "Glob pattern of input files in QIF, OFX or CSV format.")
+	outFile = flag.String("out_file", "", "Output journal file.")
 	max     = flag.Int("max", 0, "Maximum number of rows to output (0=output all)")
+	mapFile = flag.String("mapping_file", "", "Optional JSON file mapping QIF account and category names to hledger accounts.")
+	cmdty   = flag.String("commodity", "", "Default commodity symbol for amounts, eg. £ or GBP (empty=none).")
+	decls   = flag.Bool("account_directives", false, "Whether to declare every account, with its type, at the top of the output.")
+	format  = flag.String("format", "hledger", "Output journal format: hledger, ledger or beancount.")
+	column  = flag.Int("amount_column", 0, "Least column to right-align posting amounts to in hledger and ledger output, eg. 52 (0=no alignment).")
+	asserts = flag.String("assertions", "none", "Balance assertions of each account's running balance: none, every (transaction) or monthly.")
+	methods = flag.Bool("method_tags", false, "Whether to tag transactions with the payment method identified by their QIF number, eg. method:direct-debit for DD.")
+	order   = flag.String("date_order", "auto", "Order of day and month in QIF dates: dmy, mdy or auto (detected in each file).")
+	pivot   = flag.Int("century_pivot", qif.DefaultPivot, "First of the hundred years that two-digit years in QIF dates fall in.")
+	decimal = flag.String("decimal_mark", ".", "Decimal mark of amounts in QIF and CSV files, eg. , for 1.234,56.")
+	group   = flag.String("grouping_mark", "", "Digit grouping mark of amounts in QIF and CSV files (empty=, or else . if that's not the decimal mark).")
+	charset = flag.String("encoding", "auto", "Character encoding of QIF and CSV files: UTF-8, a golang.org/x/text charmap name such as windows-1252 or ISO-8859-15, or auto (detected in each file).")
+	lenient = flag.Bool("lenient", false, "Whether to tolerate blank lines, stray whitespace and split fields before any S line in QIF files, logging them and unknown fields as warnings.")
+	profile = flag.String("csv_profile", "", "Layout of CSV input files: a built-in profile (monzo, starling or barclays), or a JSON profile file (empty=detect from the header row).")
 )
 
-func main() {
-	flag.Parse()
+func loadMapping(name string) (*converter.Mapping, error) {
+	if name == "" {
+		return nil, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return converter.LoadMapping(f)
+}
 
-	fmt.Println("QIF Converter")
+// newSerializer returns the Serializer for the named format, aligning posting
+// amounts to the given column where the format allows.
+func newSerializer(format string, column int) (model.Serializer, error) {
+	serializer, err := model.NewSerializer(format)
+	switch s := serializer.(type) {
+	case *model.HledgerSerializer:
+		s.AmountColumn = column
+	case *model.LedgerSerializer:
+		s.AmountColumn = column
+	}
+	return serializer, err
+}
 
-	hlf, err := os.Create(*outFile)
+// loadProfile returns the built-in CSV profile of the given name, or else the
+// profile read from the named JSON file.  It returns nil for an empty name, so
+// that the profile is detected from each file's header row.
+func loadProfile(name string) (*csv.Profile, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if p, ok := csv.Profiles[name]; ok {
+		return p, nil
+	}
+	f, err := os.Open(name)
 	if err != nil {
-		panic(fmt.Errorf("Creating %q error: %v", *outFile, err))
+		return nil, err
 	}
-	defer hlf.Close()
+	defer f.Close()
+	return csv.LoadProfile(f)
+}
 
-	var allTxns []*model.Transaction
-	inFileNames, err := filepath.Glob(*inFiles)
+// isOFX reports whether the named file, to be read from br, is in OFX format
+// rather than QIF, judging by its extension or else its content.
+func isOFX(name string, br *bufio.Reader) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".ofx", ".qfx":
+		return true
+	case ".qif":
+		return false
+	}
+	start, _ := br.Peek(512)
+	return ofx.IsOFX(start)
+}
+
+// fileReader holds the settings for reading input files.
+type fileReader struct {
+	charset encoding.Encoding // The encoding of QIF, CSV and OFX files, or nil to detect it in each.
+	profile *csv.Profile      // The layout of CSV files, or nil to detect it.
+	dates   qif.DateDialect   // The dialect of QIF dates.
+	amounts qif.AmountFormat  // The format of QIF and CSV amounts.
+	detect  bool              // Whether to detect the order of day and month in each QIF file.
+	lenient bool              // Whether to read QIF files in lenient mode.
+}
+
+// newFileReader returns a fileReader with the settings given by flags.
+func newFileReader() (*fileReader, error) {
+	p, err := loadProfile(*profile)
 	if err != nil {
-		panic(fmt.Errorf("filepath.Glob(%q) error: %v", *inFiles, err))
+		return nil, fmt.Errorf("loading CSV profile %q: %v", *profile, err)
 	}
+	dates, detect, err := newDateDialect(*order, *pivot)
+	if err != nil {
+		return nil, err
+	}
+	amounts, err := newAmountFormat(*decimal, *group)
+	if err != nil {
+		return nil, err
+	}
+	var cs encoding.Encoding
+	if *charset != "auto" {
+		if cs, err = qif.LookupEncoding(*charset); err != nil {
+			return nil, err
+		}
+	}
+	return &fileReader{
+		charset: cs,
+		profile: p,
+		dates:   dates,
+		amounts: amounts,
+		detect:  detect,
+		lenient: *lenient,
+	}, nil
+}
 
-	decoder := charmap.ISO8859_15.NewDecoder()
+// newAmountFormat returns the QIF amount format with the given decimal and
+// grouping marks, each a single character, or empty for the default.
+func newAmountFormat(decimal, grouping string) (qif.AmountFormat, error) {
+	var af qif.AmountFormat
+	for _, m := range []struct {
+		name, s string
+		mark    *rune
+	}{{"decimal", decimal, &af.DecimalMark}, {"grouping", grouping, &af.GroupingMark}} {
+		rs := []rune(m.s)
+		switch len(rs) {
+		case 0:
+		case 1:
+			*m.mark = rs[0]
+		default:
+			return af, fmt.Errorf("%s mark %q must be a single character", m.name, m.s)
+		}
+	}
+	if af.DecimalMark != 0 && af.DecimalMark == af.GroupingMark {
+		return af, fmt.Errorf("decimal and grouping marks must differ, not both be %q", af.DecimalMark)
+	}
+	return af, nil
+}
+
+// newDateDialect returns the QIF date dialect for the given order, "dmy",
+// "mdy" or "auto", and century pivot, and whether the order is to be detected.
+func newDateDialect(order string, pivot int) (qif.DateDialect, bool, error) {
+	dd := qif.DateDialect{Pivot: pivot}
+	switch order {
+	case "dmy":
+		return dd, false, nil
+	case "mdy":
+		dd.Order = qif.MonthDay
+		return dd, false, nil
+	case "auto":
+		return dd, true, nil
+	}
+	return dd, false, fmt.Errorf("unknown date order %q: want \"dmy\", \"mdy\" or \"auto\"", order)
+}
 
-	for _, inf := range inFileNames {
+// readFile reads the named QIF, OFX or CSV file from br.  The records of a CSV
+// file are those of an account named after the file.  QIF and CSV files are
+// decoded from the encoding given, or else the one detected in each file; OFX
+// files from the encoding given, or else the one named in their header.
+func (fr *fileReader) readFile(name string, br *bufio.Reader) (*qif.File, error) {
+	csvFile := strings.ToLower(filepath.Ext(name)) == ".csv"
+	if !csvFile && isOFX(name, br) {
+		var dec *encoding.Decoder
+		if fr.charset != nil {
+			dec = fr.charset.NewDecoder()
+		}
+		return ofx.ReadFile(br, dec)
+	}
+	data, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	cs := fr.charset
+	if cs == nil {
+		cs = qif.DetectEncoding(data)
+	}
+	if csvFile {
+		account := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		f, err := csv.ReadFile(bytes.NewReader(data), fr.profile, account, cs.NewDecoder())
+		if err != nil {
+			return nil, err
+		}
+		return f, f.NormalizeAmounts(fr.amounts)
+	}
+	return fr.readQIF(name, bytes.NewReader(data), cs.NewDecoder())
+}
+
+// readQIF reads the named QIF file from r, decoded by dec, logging any
+// warnings, and rewrites its dates in the Money format that the OFX and CSV
+// readers write, and its amounts in the format that the OFX reader writes, so
+// that the converter reads every file's dates and amounts alike.
+func (fr *fileReader) readQIF(name string, r io.Reader, dec *encoding.Decoder) (*qif.File, error) {
+	f, err := qif.ReadFileWithOptions(r, dec, qif.Options{Name: name, Lenient: fr.lenient})
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range f.Warnings {
+		log.Printf(" .. warning: %v", w)
+	}
+	dd := fr.dates
+	if fr.detect {
+		if dd.Order, err = f.DateOrder(); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.NormalizeDates(dd); err != nil {
+		return nil, err
+	}
+	return f, f.NormalizeAmounts(fr.amounts)
+}
+
+// readFiles reads every account, category and class from the named QIF, OFX
+// and CSV files.
+func (fr *fileReader) readFiles(names []string) []*qif.File {
+	var qifFiles []*qif.File
+	for _, inf := range names {
 		fmt.Printf(" .. opening %s\n", inf)
 
 		qf, err := os.Open(inf)
@@ -48,35 +245,117 @@ func main() {
 		}
 		defer qf.Close()
 
-		fmt.Printf(" .. parsing QIF from %s\n", inf)
+		fmt.Printf(" .. parsing %s\n", inf)
 
-		rs, err := qif.NewRecordSet(qf, decoder)
+		qifFile, err := fr.readFile(inf, bufio.NewReader(qf))
 		if err != nil {
 			log.Fatalf("Reading file %q got error: %v", inf, err)
 		}
-		log.Printf(" .. parsed %d QIF records.", len(rs.Records))
+		log.Printf(" .. parsed %d QIF accounts, %d categories.", len(qifFile.Accounts), len(qifFile.Categories))
 
-		fmt.Printf(" .. converting to ledger from %s\n", inf)
+		qifFiles = append(qifFiles, qifFile)
+	}
+	return qifFiles
+}
 
-		txns, err := converter.FromQIF(rs)
-		if err != nil {
-			log.Fatalf("Converting QIF RecordSet got error: %v", err)
+// removeRecords removes from allSets the records that aren't to be converted,
+// logging what's removed and the transfers left unmatched.
+func removeRecords(allSets []*qif.RecordSet, opts *converter.Options) {
+	// Statements downloaded for overlapping periods repeat transactions.
+	if n := converter.RemoveDuplicates(allSets); n > 0 {
+		log.Printf(" .. removed %d duplicate transactions", n)
+	}
+	// Investment actions such as stock splits can't be converted.
+	for _, e := range converter.RemoveUnsupported(allSets) {
+		log.Printf(" .. warning: skipped %v", e)
+	}
+	// A transfer between two of the accounts appears in the records of both.
+	for _, u := range converter.MergeTransfers(allSets, opts) {
+		log.Printf(" .. unmatched transfer: %v", u)
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	fmt.Println("QIF Converter")
+
+	serializer, err := newSerializer(*format, *column)
+	if err != nil {
+		log.Fatalf("Choosing output format got error: %v", err)
+	}
+	mapping, err := loadMapping(*mapFile)
+	if err != nil {
+		log.Fatalf("Loading mapping file %q got error: %v", *mapFile, err)
+	}
+	assertions, err := converter.ParseAssertions(*asserts)
+	if err != nil {
+		log.Fatalf("Choosing balance assertions got error: %v", err)
+	}
+	opts := &converter.Options{Mapping: mapping, Commodity: *cmdty, Assertions: assertions, MethodTags: *methods}
+	fr, err := newFileReader()
+	if err != nil {
+		log.Fatalf("Choosing how to read input files got error: %v", err)
+	}
+
+	hlf, err := os.Create(*outFile)
+	if err != nil {
+		panic(fmt.Errorf("Creating %q error: %v", *outFile, err))
+	}
+	defer hlf.Close()
+
+	var allTxns []*model.Transaction
+	inFileNames, err := filepath.Glob(*inFiles)
+	if err != nil {
+		panic(fmt.Errorf("filepath.Glob(%q) error: %v", *inFiles, err))
+	}
+
+	qifFiles := fr.readFiles(inFileNames)
+
+	// Category lists from any file apply to the records of every file.
+	opts.Categories = map[string]*qif.Category{}
+	for _, qifFile := range qifFiles {
+		for _, c := range qifFile.Categories {
+			opts.Categories[c.Name] = c
 		}
-		log.Printf(" .. converted %d records for account %q.\n\n", len(txns), rs.AccountName())
+	}
+
+	var allSets []*qif.RecordSet
+	for _, qifFile := range qifFiles {
+		allSets = append(allSets, qifFile.Accounts...)
+	}
+	removeRecords(allSets, opts)
+
+	for i, qifFile := range qifFiles {
+		for _, rs := range qifFile.Accounts {
+			fmt.Printf(" .. converting to ledger %d records for account %q from %s\n", len(rs.Records), rs.AccountName(), inFileNames[i])
+
+			txns, err := converter.FromQIF(rs, opts)
+			if err != nil {
+				log.Fatalf("Converting QIF RecordSet got error: %v", err)
+			}
+			log.Printf(" .. converted %d records for account %q.\n\n", len(txns), rs.AccountName())
 
-		allTxns = append(allTxns, txns...)
+			allTxns = append(allTxns, txns...)
+		}
 	}
 
-	sort.Slice(allTxns, func(l, r int) bool {
+	sort.SliceStable(allTxns, func(l, r int) bool {
 		return allTxns[l].Date.Before(allTxns[r].Date)
 	})
 
-	for i, txn := range allTxns {
-		if err = txn.SerializeHledger(hlf); err != nil {
-			panic(err)
-		}
-		if *max > 0 && *max == i {
-			break
-		}
+	// Transfers into each account may come from the records of another.
+	converter.AssertBalances(allSets, allTxns, opts)
+
+	journal := &model.Journal{Transactions: allTxns}
+	if *max > 0 && *max < len(allTxns) {
+		journal.Transactions = allTxns[:*max]
+	}
+	// Beancount needs the account types to place accounts under its roots.
+	if *decls || *format == "beancount" {
+		journal.Accounts = converter.AccountDeclarations(allSets, journal.Transactions, opts)
+	}
+	if err = serializer.Serialize(hlf, journal); err != nil {
+		panic(err)
 	}
 }
@@ -0,0 +1,178 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Default prefixes applied to QIF names that no Rule in a Mapping matches.
+const (
+	DefaultAccountPrefix = "assets:"
+	DefaultExpensePrefix = "expenses:"
+	DefaultIncomePrefix  = "income:"
+)
+
+// uncategorised names the fallback account for amounts with no category.
+const uncategorised = "unknown"
+
+// Mapping maps QIF account and category names onto hledger account names.
+//
+// Rules are tried in order and the first that matches wins.  Names that no
+// Rule matches are given one of the fallback prefixes: AccountPrefix for QIF
+// account names, and ExpensePrefix or IncomePrefix for categories.
+//
+// Commodities gives the commodity symbol, eg. "EUR", of each QIF account that
+// isn't held in the converter's default commodity.
+type Mapping struct {
+	Rules         []*Rule           `json:"rules"`
+	AccountPrefix string            `json:"account_prefix"`
+	ExpensePrefix string            `json:"expense_prefix"`
+	IncomePrefix  string            `json:"income_prefix"`
+	Commodities   map[string]string `json:"commodities"`
+}
+
+// Rule maps the QIF names it matches onto an hledger account.  Exactly one of
+// Exact, Prefix or Regex must be set.
+//
+//   - Exact matches the whole name, which is replaced by Account.
+//   - Prefix matches the name or its leading components, which are replaced by
+//     Account and the remainder of the name kept, e.g. Prefix "Food" and
+//     Account "expenses:groceries" maps "Food:Bakery" to
+//     "expenses:groceries:Bakery", but not "Foodbank" to anything.
+//   - Regex matches the whole name; Account may refer to submatches as $1,
+//     ${name} etc. as for regexp.Regexp.Expand.
+type Rule struct {
+	Exact   string `json:"exact,omitempty"`
+	Prefix  string `json:"prefix,omitempty"`
+	Regex   string `json:"regex,omitempty"`
+	Account string `json:"account"`
+
+	re *regexp.Regexp
+}
+
+// LoadMapping reads a Mapping in JSON format from the given io.Reader.
+func LoadMapping(r io.Reader) (*Mapping, error) {
+	m := &Mapping{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("decoding mapping: %v", err)
+	}
+	for i, rule := range m.Rules {
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("mapping rule %d: %v", i, err)
+		}
+	}
+	return m, nil
+}
+
+func (r *Rule) compile() error {
+	set := 0
+	for _, s := range []string{r.Exact, r.Prefix, r.Regex} {
+		if s != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("want exactly one of exact, prefix or regex, got %d", set)
+	}
+	if r.Account == "" {
+		return fmt.Errorf("no account given")
+	}
+	if r.Regex == "" {
+		return nil
+	}
+	re, err := regexp.Compile("^(?:" + r.Regex + ")$")
+	if err != nil {
+		return err
+	}
+	r.re = re
+	return nil
+}
+
+// apply returns the hledger account for name and true if the Rule matches it.
+func (r *Rule) apply(name string) (string, bool) {
+	switch {
+	case r.Exact != "":
+		if name == r.Exact {
+			return r.Account, true
+		}
+	case r.Prefix != "":
+		if name == r.Prefix || strings.HasPrefix(name, r.Prefix+":") {
+			return r.Account + name[len(r.Prefix):], true
+		}
+	case r.re != nil:
+		if m := r.re.FindStringSubmatchIndex(name); m != nil {
+			return string(r.re.ExpandString(nil, r.Account, name, m)), true
+		}
+	}
+	return "", false
+}
+
+func (m *Mapping) lookup(name string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	for _, r := range m.Rules {
+		if ac, ok := r.apply(name); ok {
+			return ac, true
+		}
+	}
+	return "", false
+}
+
+// Account returns the hledger account for the QIF account name given.
+func (m *Mapping) Account(name string) string {
+	if ac, ok := m.lookup(name); ok {
+		return ac
+	}
+	return m.accountPrefix() + name
+}
+
+// Category returns the hledger account for the QIF category given.  isExpense
+// selects the fallback prefix used if no Rule matches.  Uncategorised amounts,
+// with no name, fall back to the account "unknown" under that prefix.
+func (m *Mapping) Category(name string, isExpense bool) string {
+	if ac, ok := m.lookup(name); ok {
+		return ac
+	}
+	if name == "" {
+		name = uncategorised
+	}
+	if isExpense {
+		return m.expensePrefix() + name
+	}
+	return m.incomePrefix() + name
+}
+
+func (m *Mapping) accountPrefix() string {
+	if m == nil || m.AccountPrefix == "" {
+		return DefaultAccountPrefix
+	}
+	return m.AccountPrefix
+}
+
+func (m *Mapping) expensePrefix() string {
+	if m == nil || m.ExpensePrefix == "" {
+		return DefaultExpensePrefix
+	}
+	return m.ExpensePrefix
+}
+
+func (m *Mapping) incomePrefix() string {
+	if m == nil || m.IncomePrefix == "" {
+		return DefaultIncomePrefix
+	}
+	return m.IncomePrefix
+}
+
+// Commodity returns the commodity symbol configured for the QIF account name
+// given, and whether there is one.
+func (m *Mapping) Commodity(account string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	sym, ok := m.Commodities[account]
+	return sym, ok
+}
@@ -0,0 +1,90 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+const testMapping = `{
+  "rules": [
+    {"exact": "Paul - Monzo Current", "account": "assets:bank:monzo:paul:current"},
+    {"exact": "Orange VISA", "account": "liabilities:bank:orange:paul:credit card"},
+    {"prefix": "Food", "account": "expenses:groceries"},
+    {"regex": "(\\w+) - Halifax (.*)", "account": "assets:bank:halifax:$1:$2"}
+  ],
+  "expense_prefix": "spend:"
+}`
+
+func TestLoadMapping(t *testing.T) {
+	tests := []struct {
+		desc    string
+		json    string
+		wantErr bool
+	}{
+		{desc: "empty mapping", json: `{}`},
+		{desc: "valid rules", json: testMapping},
+		{desc: "not JSON", json: `rules:`, wantErr: true},
+		{desc: "rule with no matcher", json: `{"rules": [{"account": "a"}]}`, wantErr: true},
+		{desc: "rule with two matchers", json: `{"rules": [{"exact": "a", "prefix": "b", "account": "a"}]}`, wantErr: true},
+		{desc: "rule with no account", json: `{"rules": [{"exact": "a"}]}`, wantErr: true},
+		{desc: "rule with bad regex", json: `{"rules": [{"regex": "(", "account": "a"}]}`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := LoadMapping(strings.NewReader(test.json))
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Errorf("LoadMapping()=_, err? %t want? %t (err=%v)", gotErr, test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestMapping(t *testing.T) {
+	m, err := LoadMapping(strings.NewReader(testMapping))
+	if err != nil {
+		t.Fatalf("LoadMapping() error: %v", err)
+	}
+	tests := []struct {
+		name         string
+		isExpense    bool
+		wantAccount  string
+		wantCategory string
+	}{
+		{"Paul - Monzo Current", false, "assets:bank:monzo:paul:current", "assets:bank:monzo:paul:current"},
+		{"Orange VISA", true, "liabilities:bank:orange:paul:credit card", "liabilities:bank:orange:paul:credit card"},
+		{"Food:Bakery", true, "expenses:groceries:Bakery", "expenses:groceries:Bakery"},
+		{"Food", true, "expenses:groceries", "expenses:groceries"},
+		{"Foodbank", true, "assets:Foodbank", "spend:Foodbank"},
+		{"Oscar - Halifax Save4It", false, "assets:bank:halifax:Oscar:Save4It", "assets:bank:halifax:Oscar:Save4It"},
+		{"Joint Account - Halifax Saver", false, "assets:Joint Account - Halifax Saver", "income:Joint Account - Halifax Saver"},
+		{"Clothes:Shoes", true, "assets:Clothes:Shoes", "spend:Clothes:Shoes"},
+		{"Salary", false, "assets:Salary", "income:Salary"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := m.Account(test.name); got != test.wantAccount {
+				t.Errorf("Account(%q)=%q want %q", test.name, got, test.wantAccount)
+			}
+			if got := m.Category(test.name, test.isExpense); got != test.wantCategory {
+				t.Errorf("Category(%q, %t)=%q want %q", test.name, test.isExpense, got, test.wantCategory)
+			}
+		})
+	}
+}
+
+func TestMapping_nil(t *testing.T) {
+	var m *Mapping
+	if got, want := m.Account("Cash"), "assets:Cash"; got != want {
+		t.Errorf("Account()=%q want %q", got, want)
+	}
+	if got, want := m.Category("Food", true), "expenses:Food"; got != want {
+		t.Errorf("Category(_, true)=%q want %q", got, want)
+	}
+	if got, want := m.Category("Salary", false), "income:Salary"; got != want {
+		t.Errorf("Category(_, false)=%q want %q", got, want)
+	}
+	if got, want := m.Category("", true), "expenses:unknown"; got != want {
+		t.Errorf("Category(\"\", true)=%q want %q", got, want)
+	}
+}
@@ -0,0 +1,200 @@
+package converter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/phad/msmtohl/model"
+	"github.com/phad/msmtohl/parser/qif"
+)
+
+// UnmatchedTransfer is a transfer record whose mirror was not found in the
+// RecordSet of the account it names, or that names an account not given.
+type UnmatchedTransfer struct {
+	Account string      // The QIF name of the account the record belongs to.
+	Record  *qif.Record // The transfer record.
+	Split   *qif.Split  // The split of Record that is the transfer, if any.
+	Missing bool        // Whether the account named is missing from the input.
+}
+
+// String conforms with Stringer, describing the transfer and why it's
+// unmatched.
+func (u *UnmatchedTransfer) String() string {
+	label, amount := u.Record.Label, u.Record.Amount
+	if u.Split != nil {
+		label, amount = u.Split.Category, u.Split.Amount
+	}
+	if u.Missing {
+		return fmt.Sprintf("%s %s %s: transfer with %q, which is not an account in the input", u.Record.Date, u.Account, amount, label)
+	}
+	return fmt.Sprintf("%s %s %s: transfer with %q has no matching record", u.Record.Date, u.Account, amount, label)
+}
+
+// transferLeg is the part of a record that transfers an amount to or from
+// another account: the whole of an unsplit record, one of its splits, or the
+// cash of an investment record.
+type transferLeg struct {
+	record  *qif.Record
+	split   *qif.Split // nil for an unsplit record.
+	account string     // The QIF name of the other account.
+	date    time.Time
+	amount  model.Decimal
+	whole   bool // Whether the leg is all there is to record, which may be removed.
+}
+
+// legKey identifies a transferLeg.
+type legKey struct {
+	record *qif.Record
+	split  *qif.Split
+}
+
+// key returns the legKey of l.
+func (l *transferLeg) key() legKey {
+	return legKey{l.record, l.split}
+}
+
+// MergeTransfers finds transfers between two of the accounts in sets that
+// appear in the records of both.  A transfer of one account, made by a record
+// or one of its splits, is mirrored by a transfer back from the account it
+// names, on the same date, for the opposite amount.  The cash that an
+// investment record moves to or from the account it names, given by its
+// TransferAmount or else its Amount, is a transfer too.  Where one of the pair
+// is an unsplit record of a non-investment account, it's removed from its
+// RecordSet, leaving a single record that converts to a transaction between
+// the two accounts.  A pair of which neither can be removed is left as it is.
+//
+// Dates are read in the dialect opts.Dates.  The transfers that have no
+// mirror, or that name an account not in sets, are returned.
+func MergeTransfers(sets []*qif.RecordSet, opts *Options) []*UnmatchedTransfer {
+	byName := map[string]*qif.RecordSet{}
+	for _, rs := range sets {
+		byName[rs.AccountName()] = rs
+	}
+	// Transfers that are part of a pair, and the records to remove.
+	matched, mirrors := map[legKey]bool{}, map[*qif.Record]bool{}
+	var unmatched []*UnmatchedTransfer
+	for _, rs := range sets {
+		name := rs.AccountName()
+		for _, r := range rs.Records {
+			for _, l := range transferLegs(rs, r, opts) {
+				other, ok := byName[l.account]
+				if matched[l.key()] || other == rs {
+					continue
+				}
+				if !ok {
+					unmatched = append(unmatched, &UnmatchedTransfer{Account: name, Record: r, Split: l.split, Missing: true})
+					continue
+				}
+				m := findMirror(l, name, other, matched, opts)
+				if m == nil {
+					unmatched = append(unmatched, &UnmatchedTransfer{Account: name, Record: r, Split: l.split})
+					continue
+				}
+				matched[l.key()], matched[m.key()] = true, true
+				if m.whole {
+					mirrors[m.record] = true
+				} else {
+					mirrors[r] = true
+				}
+			}
+		}
+	}
+	for _, rs := range sets {
+		var kept []*qif.Record
+		for _, r := range rs.Records {
+			if !mirrors[r] {
+				kept = append(kept, r)
+			}
+		}
+		rs.Records = kept
+	}
+	return unmatched
+}
+
+// transferLegs returns the transfers made by r, a record of rs: r itself if
+// it's an unsplit transfer, or else those of its splits that are.  A record of
+// an investment account makes one of the cash it moves, if it names a
+// transfer account.  Records that can't be read make none.
+func transferLegs(rs *qif.RecordSet, r *qif.Record, opts *Options) []*transferLeg {
+	if !r.Transfer && len(r.Splits) == 0 {
+		return nil
+	}
+	d, err := opts.parseDate(r.Date)
+	if err != nil {
+		return nil
+	}
+	if rs.Opening.Type == "Type:Invst" && r.Action != "Cash" {
+		amount, ok := investmentCash(r, opts)
+		if !ok {
+			return nil
+		}
+		return []*transferLeg{{record: r, account: r.Label, date: d, amount: amount}}
+	}
+	if len(r.Splits) == 0 {
+		amount, err := opts.parseAmount(r.Amount)
+		if err != nil {
+			return nil
+		}
+		return []*transferLeg{{record: r, account: r.Label, date: d, amount: amount, whole: true}}
+	}
+	amounts, err := splitAmounts(r, opts)
+	if err != nil {
+		return nil
+	}
+	var legs []*transferLeg
+	for i, s := range r.Splits {
+		if s.Transfer {
+			legs = append(legs, &transferLeg{record: r, split: s, account: s.Category, date: d, amount: amounts[i]})
+		}
+	}
+	return legs
+}
+
+// findMirror returns the first transfer of other that mirrors l, a transfer of
+// the named account, and isn't already matched.  Two transfers that aren't
+// whole records don't mirror each other, since neither could be removed.
+func findMirror(l *transferLeg, name string, other *qif.RecordSet, matched map[legKey]bool, opts *Options) *transferLeg {
+	for _, m := range other.Records {
+		for _, ml := range transferLegs(other, m, opts) {
+			if matched[ml.key()] || ml.account != name || (!l.whole && !ml.whole) {
+				continue
+			}
+			if ml.date.Equal(l.date) && ml.amount.Add(l.amount).IsZero() {
+				return ml
+			}
+		}
+	}
+	return nil
+}
+
+// investmentCash returns the cash that r, an investment record, moves into the
+// investment account from its transfer account, negative if it moves out, and
+// whether it moves any.  Shares moved between accounts aren't cash.
+func investmentCash(r *qif.Record, opts *Options) (model.Decimal, bool) {
+	action, x := splitAction(r.Action)
+	var in bool
+	switch kind := actionKinds[action]; {
+	case action == "XIn" || action == "XOut":
+		in = action == "XIn"
+	case !x:
+		return model.Decimal{}, false
+	case action == "Buy" || kind == expenseAction:
+		in = true
+	case action == "Sell" || kind == incomeAction:
+		in = false
+	default:
+		return model.Decimal{}, false
+	}
+	a := r.TransferAmount
+	if a == "" {
+		a = r.Amount
+	}
+	amount, err := opts.parseAmount(a)
+	if err != nil {
+		return model.Decimal{}, false
+	}
+	if amount = amount.Abs(); !in {
+		amount = amount.Neg()
+	}
+	return amount, true
+}
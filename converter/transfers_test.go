@@ -0,0 +1,110 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/phad/msmtohl/parser/qif"
+)
+
+func TestMergeTransfers(t *testing.T) {
+	current := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:Bank", Label: "Current", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "01/02'2016", Amount: "-100.00", Label: "Savings", Transfer: true},
+			{Date: "01/02'2016", Amount: "-100.00", Label: "Savings", Transfer: true},
+			{Date: "02/02'2016", Amount: "-20.00", Label: "Food"},
+			{Date: "03/02'2016", Amount: "-50.00", Label: "VISA", Transfer: true},
+			{Date: "04/02'2016", Amount: "-10.00", Label: "Pension", Transfer: true},
+			{Date: "05/02'2016", Amount: "-35.00", Splits: []*qif.Split{
+				{Category: "Savings", Transfer: true, Amount: "-30.00"},
+				{Category: "Food", Amount: "-5.00"},
+			}},
+			{Date: "06/02'2016", Amount: "-40.00", Splits: []*qif.Split{
+				{Category: "Savings", Transfer: true, Percent: "50"},
+				{Category: "Pension", Transfer: true, Percent: "50"},
+			}},
+		},
+	}
+	savings := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:Bank", Label: "Savings", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "01/02'2016", Amount: "100.00", Label: "Current", Transfer: true},
+			{Date: "01/02'2016", Amount: "100", Label: "Current", Transfer: true},
+			{Date: "01/02'2016", Amount: "100.00", Label: "Current", Transfer: true},
+			{Date: "05/02'2016", Amount: "30.00", Label: "Current", Transfer: true},
+			{Date: "06/02'2016", Amount: "20.00", Label: "Current", Transfer: true},
+		},
+	}
+	visa := &qif.RecordSet{
+		Account: &qif.Account{Name: "VISA", Type: "CCard"},
+		Opening: &qif.Record{Type: "Type:CCard", Label: "VISA", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "04/02'2016", Amount: "50.00", Label: "Current", Transfer: true},
+		},
+	}
+	wantRecords := map[*qif.RecordSet][]*qif.Record{
+		current: current.Records,
+		savings: savings.Records[2:3],
+		visa:    visa.Records,
+	}
+	wantUnmatched := []*UnmatchedTransfer{
+		{Account: "Current", Record: current.Records[3]},
+		{Account: "Current", Record: current.Records[4], Missing: true},
+		{Account: "Current", Record: current.Records[6], Split: current.Records[6].Splits[1], Missing: true},
+		{Account: "Savings", Record: savings.Records[2]},
+		{Account: "VISA", Record: visa.Records[0]},
+	}
+
+	got := MergeTransfers([]*qif.RecordSet{current, savings, visa}, nil)
+	if !reflect.DeepEqual(got, wantUnmatched) {
+		t.Errorf("MergeTransfers()=%v want %v", got, wantUnmatched)
+	}
+	for rs, want := range wantRecords {
+		if !reflect.DeepEqual(rs.Records, want) {
+			t.Errorf("MergeTransfers() left %s records %v want %v", rs.AccountName(), rs.Records, want)
+		}
+	}
+}
+
+func TestMergeTransfers_investment(t *testing.T) {
+	current := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:Bank", Label: "Current", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "01/03'2016", Amount: "-100.00", Label: "ISA", Transfer: true},
+			{Date: "02/03'2016", Amount: "-505.00", Label: "ISA", Transfer: true},
+			{Date: "03/03'2016", Amount: "595.00", Label: "ISA", Transfer: true},
+			{Date: "04/03'2016", Amount: "12.34", Label: "ISA", Transfer: true},
+			{Date: "05/03'2016", Amount: "-7.00", Label: "ISA", Transfer: true},
+			{Date: "06/03'2016", Amount: "-3.00", Label: "ISA", Transfer: true},
+		},
+	}
+	isa := &qif.RecordSet{
+		Opening: &qif.Record{Type: "Type:Invst", Label: "ISA", Transfer: true},
+		Records: []*qif.Record{
+			{Date: "01/03'2016", Action: "XIn", Amount: "100.00", TransferAmount: "100.00", Label: "Current", Transfer: true},
+			{Date: "02/03'2016", Action: "BuyX", Security: "VWRL", Quantity: "10", Amount: "505.00", TransferAmount: "505.00", Label: "Current", Transfer: true},
+			{Date: "03/03'2016", Action: "SellX", Security: "VWRL", Quantity: "10", Amount: "595.00", Label: "Current", Transfer: true},
+			{Date: "04/03'2016", Action: "DivX", Security: "VWRL", Amount: "12.34", TransferAmount: "12.34", Label: "Current", Transfer: true},
+			{Date: "05/03'2016", Action: "ShrsIn", Security: "VWRL", Quantity: "1", Label: "Current", Transfer: true},
+			{Date: "06/03'2016", Action: "Cash", Amount: "3.00", Label: "Current", Transfer: true},
+		},
+	}
+	wantRecords := map[*qif.RecordSet][]*qif.Record{
+		current: current.Records[4:],
+		isa:     isa.Records[:5],
+	}
+	wantUnmatched := []*UnmatchedTransfer{
+		{Account: "Current", Record: current.Records[4]},
+	}
+
+	got := MergeTransfers([]*qif.RecordSet{current, isa}, nil)
+	if !reflect.DeepEqual(got, wantUnmatched) {
+		t.Errorf("MergeTransfers()=%v want %v", got, wantUnmatched)
+	}
+	for rs, want := range wantRecords {
+		if !reflect.DeepEqual(rs.Records, want) {
+			t.Errorf("MergeTransfers() left %s records %v want %v", rs.AccountName(), rs.Records, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Commodity is the currency or other unit an Amount is measured in, along with
+// the style used to write it.
+type Commodity struct {
+	Symbol string // The commodity symbol, eg. "£" or "EUR".  Empty for none.
+	Suffix bool   // Whether the symbol is written after the quantity.
+	Spaced bool   // Whether a space separates the symbol from the quantity.
+}
+
+// NewCommodity returns a Commodity for the given symbol, styled the way hledger
+// conventionally writes it: single currency signs precede the quantity with no
+// space, as in "£12.34", while codes and names follow it after a space, as in
+// "100.00 EUR".
+func NewCommodity(symbol string) Commodity {
+	r := []rune(symbol)
+	if len(r) == 0 || len(r) == 1 && unicode.Is(unicode.Sc, r[0]) {
+		return Commodity{Symbol: symbol}
+	}
+	return Commodity{Symbol: symbol, Suffix: true, Spaced: true}
+}
+
+// String conforms with Stringer for Commodity values.  Symbols containing
+// characters that hledger does not allow in a bare symbol are double-quoted.
+func (c Commodity) String() string {
+	if strings.IndexFunc(c.Symbol, needsQuote) >= 0 {
+		return `"` + c.Symbol + `"`
+	}
+	return c.Symbol
+}
+
+func needsQuote(r rune) bool {
+	return unicode.IsDigit(r) || unicode.IsSpace(r) || strings.ContainsRune(`-+.,@*;"'{}[]()=/`, r)
+}
+
+// Amount is a quantity of some Commodity.
+type Amount struct {
+	Quantity  Decimal
+	Commodity Commodity
+}
+
+// String conforms with Stringer for Amount values, writing them in hledger
+// amount syntax, e.g. "-£12.34" or "100.00 EUR".
+func (a Amount) String() string {
+	if a.Commodity.Symbol == "" {
+		return a.Quantity.String()
+	}
+	sep := ""
+	if a.Commodity.Spaced {
+		sep = " "
+	}
+	if a.Commodity.Suffix {
+		return a.Quantity.String() + sep + a.Commodity.String()
+	}
+	sign := ""
+	if a.Quantity.Sign() < 0 {
+		sign = "-"
+	}
+	return sign + a.Commodity.String() + sep + a.Quantity.Abs().String()
+}
+
+// ParseAmount parses an amount written in hledger amount syntax, such as
+// "-£12.34", "£-12.34", "£1,234.56", "100.00 EUR" or `10 "VWRL 2"`, along
+// with the style its commodity is written in.  Commas may group the digits
+// before the decimal point in threes.
+func ParseAmount(s string) (Amount, error) {
+	rest := strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "+") {
+		neg, rest = rest[0] == '-', rest[1:]
+	}
+	var c Commodity
+	if rest != "" && !strings.ContainsRune("0123456789.", rune(rest[0])) {
+		sym, after, err := commoditySymbol(rest)
+		if err != nil {
+			return Amount{}, fmt.Errorf("amount %q: %v", s, err)
+		}
+		rest = strings.TrimLeft(after, " ")
+		c = Commodity{Symbol: sym, Spaced: len(rest) < len(after)}
+	}
+	n := 0
+	if strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "+") {
+		n++
+	}
+	for n < len(rest) && strings.ContainsRune("0123456789.,", rune(rest[n])) {
+		n++
+	}
+	num, after := rest[:n], rest[n:]
+	if rest = strings.TrimLeft(after, " "); c.Symbol == "" && rest != "" {
+		sym, r, err := commoditySymbol(rest)
+		if err != nil {
+			return Amount{}, fmt.Errorf("amount %q: %v", s, err)
+		}
+		c = Commodity{Symbol: sym, Suffix: true, Spaced: len(rest) < len(after)}
+		rest = r
+	}
+	if rest != "" {
+		return Amount{}, fmt.Errorf("amount %q: unexpected %q", s, rest)
+	}
+	num, err := ungroup(num)
+	if err != nil {
+		return Amount{}, fmt.Errorf("amount %q: %v", s, err)
+	}
+	q, err := ParseDecimal(num)
+	if err != nil {
+		return Amount{}, err
+	}
+	if neg {
+		q = q.Neg()
+	}
+	return Amount{Quantity: q, Commodity: c}, nil
+}
+
+// ungroup returns the number num without the commas grouping the digits of its
+// integer part, which must separate groups of three.
+func ungroup(num string) (string, error) {
+	if !strings.Contains(num, ",") {
+		return num, nil
+	}
+	integer, fraction := num, ""
+	if i := strings.IndexByte(num, '.'); i >= 0 {
+		integer, fraction = num[:i], num[i:]
+	}
+	groups := strings.Split(integer, ",")
+	first := strings.TrimLeft(groups[0], "+-")
+	if first == "" || len(first) > 3 || strings.Contains(fraction, ",") {
+		return "", fmt.Errorf("misplaced grouping mark in %q", num)
+	}
+	for _, g := range groups[1:] {
+		if len(g) != 3 {
+			return "", fmt.Errorf("misplaced grouping mark in %q", num)
+		}
+	}
+	return strings.Join(groups, "") + fraction, nil
+}
+
+// commoditySymbol returns the commodity symbol at the start of s, and the rest
+// of s after it.  The symbol is either double-quoted or runs up to the first
+// character that would need quoting.
+func commoditySymbol(s string) (string, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		i := strings.IndexByte(s[1:], '"')
+		if i < 0 {
+			return "", "", fmt.Errorf("unterminated commodity symbol %s", s)
+		}
+		return s[1 : i+1], s[i+2:], nil
+	}
+	i := strings.IndexFunc(s, needsQuote)
+	switch {
+	case i == 0:
+		return "", "", fmt.Errorf("missing commodity symbol at %q", s)
+	case i < 0:
+		i = len(s)
+	}
+	return s[:i], s[i:], nil
+}
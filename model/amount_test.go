@@ -0,0 +1,84 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestNewCommodity(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   Commodity
+	}{
+		{"", Commodity{}},
+		{"£", Commodity{Symbol: "£"}},
+		{"$", Commodity{Symbol: "$"}},
+		{"€", Commodity{Symbol: "€"}},
+		{"EUR", Commodity{Symbol: "EUR", Suffix: true, Spaced: true}},
+		{"US$", Commodity{Symbol: "US$", Suffix: true, Spaced: true}},
+	}
+	for _, test := range tests {
+		if got := NewCommodity(test.symbol); got != test.want {
+			t.Errorf("NewCommodity(%q)=%+v want %+v", test.symbol, got, test.want)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		want   string
+	}{
+		{Amount{Quantity: MustParseDecimal("12.34")}, "12.34"},
+		{Amount{Quantity: MustParseDecimal("-12.34")}, "-12.34"},
+		{Amount{MustParseDecimal("12.34"), NewCommodity("£")}, "£12.34"},
+		{Amount{MustParseDecimal("-12.34"), NewCommodity("£")}, "-£12.34"},
+		{Amount{MustParseDecimal("100.00"), NewCommodity("EUR")}, "100.00 EUR"},
+		{Amount{MustParseDecimal("-100.00"), NewCommodity("EUR")}, "-100.00 EUR"},
+		{Amount{MustParseDecimal("5"), Commodity{Symbol: "USD", Spaced: true}}, "USD 5"},
+		{Amount{MustParseDecimal("10"), NewCommodity("VWRL 2")}, `10 "VWRL 2"`},
+	}
+	for _, test := range tests {
+		if got := test.amount.String(); got != test.want {
+			t.Errorf("%+v.String()=%q want %q", test.amount, got, test.want)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Amount
+		wantErr bool
+	}{
+		{in: "12.34", want: Amount{Quantity: MustParseDecimal("12.34")}},
+		{in: "-12.34", want: Amount{Quantity: MustParseDecimal("-12.34")}},
+		{in: "-£12.34", want: Amount{MustParseDecimal("-12.34"), NewCommodity("£")}},
+		{in: "£-12.34", want: Amount{MustParseDecimal("-12.34"), NewCommodity("£")}},
+		{in: "100.00 EUR", want: Amount{MustParseDecimal("100.00"), NewCommodity("EUR")}},
+		{in: "-100.00EUR", want: Amount{MustParseDecimal("-100.00"), Commodity{Symbol: "EUR", Suffix: true}}},
+		{in: "USD 5", want: Amount{MustParseDecimal("5"), Commodity{Symbol: "USD", Spaced: true}}},
+		{in: ` 10 "VWRL 2" `, want: Amount{MustParseDecimal("10"), NewCommodity("VWRL 2")}},
+		{in: "", wantErr: true},
+		{in: "£", wantErr: true},
+		{in: "12.34 EUR GBP", wantErr: true},
+		{in: `10 "VWRL`, wantErr: true},
+		{in: "£1,234.56", want: Amount{MustParseDecimal("1234.56"), NewCommodity("£")}},
+		{in: "-1,234,567 EUR", want: Amount{MustParseDecimal("-1234567"), NewCommodity("EUR")}},
+		{in: "£-1,234", want: Amount{MustParseDecimal("-1234"), NewCommodity("£")}},
+		{in: "12,34", wantErr: true},
+		{in: "1,2345.00", wantErr: true},
+		{in: "1234,567", wantErr: true},
+		{in: ",123", wantErr: true},
+		{in: "1,234.567,8", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseAmount(test.in)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("ParseAmount(%q)=_, err? %t want? %t (err=%v)", test.in, gotErr, test.wantErr, err)
+			continue
+		}
+		if err == nil && got != test.want {
+			t.Errorf("ParseAmount(%q)=%+v want %+v", test.in, got, test.want)
+		}
+	}
+}
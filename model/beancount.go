@@ -0,0 +1,289 @@
+package model
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// BeancountSerializer writes journals in the Beancount format.  Account names
+// are rewritten to follow Beancount's rules: each component is capitalised,
+// with no spaces, under one of its five root accounts.  Every account is
+// opened on the date of its first use.
+type BeancountSerializer struct {
+	// Currency is written for amounts with no commodity, which Beancount
+	// doesn't allow.  The default is XXX, the ISO 4217 code for no currency.
+	Currency string
+}
+
+// beancountCurrencies gives the Beancount currency for common currency signs.
+var beancountCurrencies = map[string]string{
+	"£": "GBP",
+	"$": "USD",
+	"€": "EUR",
+	"¥": "JPY",
+}
+
+// beancountRoots gives the Beancount root account for conventional top-level
+// account names.
+var beancountRoots = map[string]string{
+	"assets":      "Assets",
+	"liabilities": "Liabilities",
+	"equity":      "Equity",
+	"income":      "Income",
+	"revenue":     "Income",
+	"expenses":    "Expenses",
+}
+
+// beancountTypeRoots gives the Beancount root account for accounts of each
+// type whose names don't start with a root.
+var beancountTypeRoots = map[AccountType]string{
+	Asset:     "Assets",
+	Cash:      "Assets",
+	Liability: "Liabilities",
+	Equity:    "Equity",
+	Revenue:   "Income",
+	Expense:   "Expenses",
+}
+
+// Serialize writes an open directive for every account declared in j or
+// posted to by its Transactions, followed by the Transactions.  A balance
+// assertion becomes a balance directive on the next day, written only for the
+// last assertion on an account each day.
+func (s *BeancountSerializer) Serialize(w io.Writer, j *Journal) error {
+	types := map[string]AccountType{}
+	for _, a := range j.Accounts {
+		types[strings.Join(a.Account, ":")] = a.Type
+	}
+	name := func(a Account) string {
+		return beancountAccount(a, types[strings.Join(a, ":")])
+	}
+
+	opened := map[string]time.Time{}
+	balances := map[string]*Posting{} // The last asserting Posting by account and day.
+	for _, t := range j.Transactions {
+		for i := range t.Postings {
+			p := &t.Postings[i]
+			n := name(p.Account)
+			if d, ok := opened[n]; !ok || t.Date.Before(d) {
+				opened[n] = t.Date
+			}
+			if p.Assertion != nil {
+				balances[n+t.Date.Format(" 2006-01-02")] = p
+			}
+		}
+	}
+	var first time.Time
+	if len(j.Transactions) > 0 {
+		first = j.Transactions[0].Date
+	}
+	for _, a := range j.Accounts {
+		if n := name(a.Account); opened[n].IsZero() {
+			opened[n] = first
+		}
+	}
+	var names []string
+	for n := range opened {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if _, err := fmt.Fprintf(w, "%s open %s\n", opened[n].Format("2006-01-02"), n); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range j.Transactions {
+		tags := t.Tags
+		if len(t.Code) > 0 {
+			// Beancount has no transaction code, so it's kept as metadata.
+			tags = append([]Tag{{Name: "code", Value: t.Code}}, tags...)
+		}
+		lines := append([]string{"", s.topLine(t)}, beancountComment("  ", t.Comment, tags)...)
+		var asserts []string
+		for i, p := range t.Postings {
+			n := name(p.Account)
+			lines = append(lines, "  "+s.postingLine(n, &p, i == len(t.Postings)-1))
+			lines = append(lines, beancountComment("    ", p.Comment, p.Tags)...)
+			if p.Assertion != nil && balances[n+t.Date.Format(" 2006-01-02")] == &t.Postings[i] {
+				asserts = append(asserts, fmt.Sprintf("%s balance %s  %s", t.Date.AddDate(0, 0, 1).Format("2006-01-02"), n, s.amount(*p.Assertion)))
+			}
+		}
+		if len(asserts) > 0 {
+			lines = append(lines, "")
+			lines = append(lines, asserts...)
+		}
+		if _, err := io.WriteString(w, strings.Join(lines, "\n")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// topLine returns the first line of a Transaction: its date, flag and quoted
+// payee and narration.
+func (s *BeancountSerializer) topLine(t *Transaction) string {
+	flag := "txn"
+	if t.Status == Pending || t.Status == Cleared {
+		flag = t.Status.String()
+	}
+	items := []string{t.Date.Format("2006-01-02"), flag}
+	if len(t.Payee) > 0 {
+		items = append(items, beancountString(t.Payee))
+	}
+	return strings.Join(append(items, beancountString(t.Description)), " ")
+}
+
+// postingLine returns a posting line for p, to the named account, flagged with
+// its status.  As in the hledger format the last posting's amount is elided,
+// unless it has a balance assertion to be checked.
+func (s *BeancountSerializer) postingLine(name string, p *Posting, last bool) string {
+	if p.Status == Pending || p.Status == Cleared {
+		name = p.Status.String() + " " + name
+	}
+	if last && p.Assertion == nil {
+		return name
+	}
+	line := name + "  " + s.amount(p.Amount)
+	if p.Cost != nil {
+		at := "@"
+		if p.TotalCost {
+			at = "@@"
+		}
+		line += " " + at + " " + s.amount(*p.Cost)
+	}
+	return line
+}
+
+// amount returns a in Beancount amount syntax, eg. "-12.34 GBP".
+func (s *BeancountSerializer) amount(a Amount) string {
+	return a.Quantity.String() + " " + s.currency(a.Commodity)
+}
+
+// maxCurrency is the longest currency name Beancount allows.
+const maxCurrency = 24
+
+// currency returns the Beancount currency for c: the code for a currency
+// sign, or else its symbol in capitals with any characters Beancount doesn't
+// allow replaced.  A symbol too long for Beancount is shortened, ending in a
+// hash of the whole symbol so that long symbols sharing a prefix stay apart.
+func (s *BeancountSerializer) currency(c Commodity) string {
+	if c.Symbol == "" {
+		if s.Currency == "" {
+			return "XXX"
+		}
+		return s.Currency
+	}
+	if cur, ok := beancountCurrencies[c.Symbol]; ok {
+		return cur
+	}
+	cur := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("'._-", r):
+			return r
+		}
+		return '-'
+	}, strings.ToUpper(c.Symbol))
+	if cur[0] < 'A' || cur[0] > 'Z' {
+		cur = "X" + cur
+	}
+	if last := cur[len(cur)-1]; len(cur) < 2 || !(last >= 'A' && last <= 'Z' || last >= '0' && last <= '9') {
+		cur += "X"
+	}
+	if len(cur) > maxCurrency {
+		h := fnv.New32a()
+		h.Write([]byte(c.Symbol))
+		cur = fmt.Sprintf("%s-%08X", cur[:maxCurrency-9], h.Sum32())
+	}
+	return cur
+}
+
+// beancountAccount returns the Beancount name of the Account a, of the given
+// type.  Accounts not under a conventional top-level account are placed
+// under the root for their type, or Equity if that's not known.
+func beancountAccount(a Account, typ AccountType) string {
+	var parts []string
+	for _, n := range a {
+		if c := beancountComponent(n); c != "" {
+			parts = append(parts, c)
+		}
+	}
+	if len(parts) > 0 {
+		if root, ok := beancountRoots[strings.ToLower(parts[0])]; ok {
+			parts[0] = root
+		} else {
+			root, ok := beancountTypeRoots[typ]
+			if !ok {
+				root = "Equity"
+			}
+			parts = append([]string{root}, parts...)
+		}
+	}
+	if len(parts) < 2 {
+		parts = append(parts, "Unknown")
+	}
+	return strings.Join(parts, ":")
+}
+
+// beancountComponent returns the component n of an account name capitalised,
+// with each run of characters other than letters and digits replaced by -.
+func beancountComponent(n string) string {
+	words := strings.FieldsFunc(n, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	c := []rune(strings.Join(words, "-"))
+	if len(c) == 0 {
+		return ""
+	}
+	c[0] = unicode.ToUpper(c[0])
+	return string(c)
+}
+
+// beancountComment returns metadata lines for the given tags, followed by the
+// lines of comment, each with the given indent.
+func beancountComment(indent, comment string, tags []Tag) []string {
+	var lines []string
+	for _, tag := range tags {
+		lines = append(lines, indent+beancountKey(tag.Name)+": "+beancountString(tag.Value))
+	}
+	if comment != "" {
+		for _, c := range strings.Split(comment, "\n") {
+			lines = append(lines, indent+"; "+c)
+		}
+	}
+	return lines
+}
+
+// beancountKey returns the tag name n as a Beancount metadata key, which must
+// start with a lower case letter, contain only letters, digits, - and _, and be
+// at least two characters long.
+func beancountKey(n string) string {
+	k := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '-'
+	}, n)
+	switch {
+	case k == "":
+		return "xx"
+	case k[0] >= 'A' && k[0] <= 'Z':
+		k = strings.ToLower(k[:1]) + k[1:]
+	case k[0] < 'a' || k[0] > 'z':
+		k = "x" + k
+	}
+	if len(k) < 2 {
+		k += "x"
+	}
+	return k
+}
+
+// beancountString returns s as a double-quoted Beancount string.
+func beancountString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
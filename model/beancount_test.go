@@ -0,0 +1,151 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestBeancountSerializer(t *testing.T) {
+	d2 := time.Date(2017, time.January, 13, 0, 0, 0, 0, time.UTC)
+	pound := NewCommodity("£")
+	j := &Journal{
+		Accounts: []*AccountDeclaration{
+			{Account: Account{"assets", "Current Account"}, Type: Cash},
+			{Account: Account{"misc", "refunds"}, Type: Expense},
+			{Account: Account{"assets", "Premium Bonds"}, Type: Asset},
+		},
+		Transactions: []*Transaction{
+			{
+				Date:  d1,
+				Payee: `Dave's "Deli"`,
+				Postings: []Posting{
+					{Account: Account{"misc", "refunds"}, Amount: Amount{MustParseDecimal("10.00"), pound}},
+					{Account: Account{"assets", "Current Account"}, Amount: Amount{MustParseDecimal("-10.00"), pound}, Assertion: &Amount{MustParseDecimal("90.00"), pound}},
+				},
+			},
+			{
+				Date:        d2,
+				Status:      Cleared,
+				Description: "Buy shares",
+				Comment:     "ISA",
+				Tags:        []Tag{{Name: "Broker Ref", Value: "A1"}},
+				Postings: []Posting{
+					{Status: Cleared, Comment: "monthly", Account: Account{"assets", "ISA"}, Amount: Amount{MustParseDecimal("2"), NewCommodity("VWRL 2")}, Cost: &Amount{MustParseDecimal("50.00"), pound}},
+					{Account: Account{"transfer_account"}, Amount: Amount{Quantity: MustParseDecimal("5")}},
+					{Account: Account{"assets", "Current Account"}, Amount: Amount{MustParseDecimal("-100.00"), pound}, Assertion: &Amount{MustParseDecimal("-20.00"), pound}},
+				},
+			},
+			{
+				Date:   d2,
+				Status: Pending,
+				Code:   "TFR",
+				Payee:  "Refund",
+				Postings: []Posting{
+					{Account: Account{"misc", "refunds"}, Amount: Amount{MustParseDecimal("-5.00"), pound}},
+					{Account: Account{"assets", "Current Account"}, Amount: Amount{MustParseDecimal("5.00"), pound}, Assertion: &Amount{MustParseDecimal("-15.00"), pound}},
+				},
+			},
+		},
+	}
+	want := `2017-01-12 open Assets:Current-Account
+2017-01-13 open Assets:ISA
+2017-01-12 open Assets:Premium-Bonds
+2017-01-13 open Equity:Transfer-account
+2017-01-12 open Expenses:Misc:Refunds
+
+2017-01-12 txn "Dave's \"Deli\"" ""
+  Expenses:Misc:Refunds  10.00 GBP
+  Assets:Current-Account  -10.00 GBP
+
+2017-01-13 balance Assets:Current-Account  90.00 GBP
+
+2017-01-13 * "Buy shares"
+  broker-Ref: "A1"
+  ; ISA
+  * Assets:ISA  2 VWRL-2 @ 50.00 GBP
+    ; monthly
+  Equity:Transfer-account  5 XXX
+  Assets:Current-Account  -100.00 GBP
+
+2017-01-13 ! "Refund" ""
+  code: "TFR"
+  Expenses:Misc:Refunds  -5.00 GBP
+  Assets:Current-Account  5.00 GBP
+
+2017-01-14 balance Assets:Current-Account  -15.00 GBP
+`
+	var got bytes.Buffer
+	if err := (&BeancountSerializer{}).Serialize(&got, j); err != nil {
+		t.Fatalf("Serialize() error: %v", err)
+	}
+	if got.String() != want {
+		t.Errorf("Serialize()=\n%s\nwant:\n%s", got.String(), want)
+	}
+}
+
+func TestBeancountAccount(t *testing.T) {
+	tests := []struct {
+		account Account
+		typ     AccountType
+		want    string
+	}{
+		{Account{"assets", "bank", "smile", "paul", "current"}, Cash, "Assets:Bank:Smile:Paul:Current"},
+		{Account{"liabilities", "Orange VISA"}, UntypedAccount, "Liabilities:Orange-VISA"},
+		{Account{"revenue", "salary_2017"}, Revenue, "Income:Salary-2017"},
+		{Account{"Food", "Café & Bar"}, Expense, "Expenses:Food:Café-Bar"},
+		{Account{"transfer_account"}, UntypedAccount, "Equity:Transfer-account"},
+		{Account{"equity"}, Equity, "Equity:Unknown"},
+	}
+	for _, test := range tests {
+		if got := beancountAccount(test.account, test.typ); got != test.want {
+			t.Errorf("beancountAccount(%v, %v)=%q want %q", test.account, test.typ, got, test.want)
+		}
+	}
+}
+
+func TestBeancountKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"shop", "shop"},
+		{"Broker Ref", "broker-Ref"},
+		{"2fa", "x2fa"},
+		{"A", "ax"},
+		{"", "xx"},
+	}
+	for _, test := range tests {
+		if got := beancountKey(test.name); got != test.want {
+			t.Errorf("beancountKey(%q)=%q want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBeancountCurrency(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   string
+	}{
+		{"£", "GBP"},
+		{"", "XXX"},
+		{"VWRL 2", "VWRL-2"},
+		{"2x", "X2X"},
+		{"V", "VX"},
+		{"Vanguard FTSE All-World UCITS ETF", "VANGUARD-FTSE-A-7E024656"},
+	}
+	for _, test := range tests {
+		got := (&BeancountSerializer{}).currency(NewCommodity(test.symbol))
+		if got != test.want {
+			t.Errorf("currency(%q)=%q want %q", test.symbol, got, test.want)
+		}
+		if len(got) > maxCurrency {
+			t.Errorf("currency(%q)=%q is longer than %d", test.symbol, got, maxCurrency)
+		}
+	}
+	a := (&BeancountSerializer{}).currency(NewCommodity("Vanguard FTSE All-World UCITS ETF Acc"))
+	b := (&BeancountSerializer{}).currency(NewCommodity("Vanguard FTSE All-World UCITS ETF Dist"))
+	if a == b {
+		t.Errorf("currency() of two long symbols sharing a prefix are both %q", a)
+	}
+}
@@ -0,0 +1,284 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// maxScale bounds the number of decimal places a parsed Decimal may carry, so
+// that the product of two Decimals can still be rescaled using pow10.
+const maxScale = 9
+
+var pow10 = func() [2*maxScale + 1]int64 {
+	var p [2*maxScale + 1]int64
+	p[0] = 1
+	for i := 1; i < len(p); i++ {
+		p[i] = p[i-1] * 10
+	}
+	return p
+}()
+
+// Decimal is an exact fixed-point decimal number: an integer count of units of
+// 10^-scale.  The scale of a parsed Decimal is the number of digits after its
+// decimal point, so "1234.50" has scale 2 and is printed back as "1234.50".
+// The zero value is the number 0.
+type Decimal struct {
+	units int64
+	scale int
+}
+
+// NewDecimal returns the Decimal units * 10^-scale.  Like a parsed Decimal, it
+// may have up to maxScale decimal places; it panics if scale is negative or
+// more than that.
+func NewDecimal(units int64, scale int) Decimal {
+	if scale < 0 || scale > maxScale {
+		panic(fmt.Sprintf("model: NewDecimal(%d, %d): scale must be 0 to %d", units, scale, maxScale))
+	}
+	return Decimal{units: units, scale: scale}
+}
+
+// ParseDecimal parses a decimal number of the form [+-]digits[.digits].  The
+// scale of the result is the number of digits after the decimal point.
+func ParseDecimal(s string) (Decimal, error) {
+	in := s
+	neg := false
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", in)
+	}
+	if len(fracPart) > maxScale {
+		return Decimal{}, fmt.Errorf("invalid decimal %q: more than %d decimal places", in, maxScale)
+	}
+	var units int64
+	for _, c := range intPart + fracPart {
+		if c < '0' || c > '9' {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", in)
+		}
+		if units > (1<<63-1-9)/10 {
+			return Decimal{}, fmt.Errorf("invalid decimal %q: out of range", in)
+		}
+		units = units*10 + int64(c-'0')
+	}
+	if neg {
+		units = -units
+	}
+	return Decimal{units: units, scale: len(fracPart)}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics if s cannot be parsed.  It
+// is intended for constants in code and tests.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Scale returns the number of decimal places held by d.
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Sign returns -1, 0 or +1 depending on whether d is negative, zero or positive.
+func (d Decimal) Sign() int {
+	switch {
+	case d.units < 0:
+		return -1
+	case d.units > 0:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether d is zero, at any scale.
+func (d Decimal) IsZero() bool {
+	return d.units == 0
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{units: -d.units, scale: d.scale}
+}
+
+// Abs returns the absolute value of d.
+func (d Decimal) Abs() Decimal {
+	if d.units < 0 {
+		return d.Neg()
+	}
+	return d
+}
+
+// maxResultScale bounds the scale of the results of arithmetic on Decimals:
+// that of the product of two parsed Decimals.
+const maxResultScale = 2 * maxScale
+
+// rescale returns d's units at the larger scale given, and whether they fit in
+// an int64.
+func (d Decimal) rescale(scale int) (int64, bool) {
+	return mul64(d.units, pow10[scale-d.scale])
+}
+
+// bigUnits returns d's units at the larger scale given.
+func (d Decimal) bigUnits(scale int) *big.Int {
+	p := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale-d.scale)), nil)
+	return p.Mul(p, big.NewInt(d.units))
+}
+
+// mul64 returns a * b, and whether it fits in an int64.
+func mul64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	p := a * b
+	if p/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return p, true
+}
+
+// add64 returns a + b, and whether it fits in an int64.
+func add64(a, b int64) (int64, bool) {
+	s := a + b
+	if (a > 0 && b > 0 && s < 0) || (a < 0 && b < 0 && s >= 0) {
+		return 0, false
+	}
+	return s, true
+}
+
+// fromBig returns the Decimal u * 10^-scale at the largest scale, no more than
+// maxResultScale, at which its units fit in an int64, rounding halves away
+// from zero.  It panics if the units don't fit even at scale 0.
+func fromBig(u *big.Int, scale int) Decimal {
+	target := scale
+	if target > maxResultScale {
+		target = maxResultScale
+	}
+	for ; target >= 0; target-- {
+		if q := roundBig(u, scale-target); q.IsInt64() {
+			return Decimal{units: q.Int64(), scale: target}
+		}
+	}
+	panic(fmt.Sprintf("model: decimal %se-%d out of range", u, scale))
+}
+
+// roundBig returns u / 10^n, rounded with halves away from zero.
+func roundBig(u *big.Int, n int) *big.Int {
+	if n == 0 {
+		return u
+	}
+	p := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+	q, r := new(big.Int).QuoRem(u, p, new(big.Int))
+	if r.Abs(r).Lsh(r, 1).Cmp(p) >= 0 {
+		q.Add(q, big.NewInt(int64(u.Sign())))
+	}
+	return q
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Add returns d + e, at the larger of the two scales, or if the sum is too
+// large for that the largest scale it fits at.
+func (d Decimal) Add(e Decimal) Decimal {
+	s := maxInt(d.scale, e.scale)
+	a, aok := d.rescale(s)
+	b, bok := e.rescale(s)
+	if aok && bok {
+		if sum, ok := add64(a, b); ok {
+			return Decimal{units: sum, scale: s}
+		}
+	}
+	return fromBig(new(big.Int).Add(d.bigUnits(s), e.bigUnits(s)), s)
+}
+
+// Sub returns d - e, at the larger of the two scales, as for Add.
+func (d Decimal) Sub(e Decimal) Decimal {
+	return d.Add(e.Neg())
+}
+
+// Mul returns d * e, at the sum of the two scales, or if the product is too
+// large for that, or that is more than twice maxScale, the largest scale it
+// fits at.
+func (d Decimal) Mul(e Decimal) Decimal {
+	return product(d, e, 0)
+}
+
+// Percent returns pct percent of d, at the sum of the two scales plus two, as
+// for Mul.
+func (d Decimal) Percent(pct Decimal) Decimal {
+	return product(d, pct, 2)
+}
+
+// product returns d * e * 10^-shift, at the sum of the scales plus shift if it
+// fits, as for Mul.
+func product(d, e Decimal, shift int) Decimal {
+	s := d.scale + e.scale + shift
+	if p, ok := mul64(d.units, e.units); ok && s <= maxResultScale {
+		return Decimal{units: p, scale: s}
+	}
+	return fromBig(new(big.Int).Mul(big.NewInt(d.units), big.NewInt(e.units)), s)
+}
+
+// Cmp compares d and e, returning -1 if d < e, 0 if d == e and +1 if d > e.
+func (d Decimal) Cmp(e Decimal) int {
+	s := maxInt(d.scale, e.scale)
+	a, aok := d.rescale(s)
+	b, bok := e.rescale(s)
+	switch {
+	case !aok || !bok:
+		return d.bigUnits(s).Cmp(e.bigUnits(s))
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Equal reports whether d and e are numerically equal, regardless of scale.
+func (d Decimal) Equal(e Decimal) bool {
+	return d.Cmp(e) == 0
+}
+
+// Round returns d rounded to the given scale, with halves rounded away from
+// zero.  A scale larger than d's pads d with zeros, as far as it fits.  It
+// panics if scale is negative or more than twice maxScale.
+func (d Decimal) Round(scale int) Decimal {
+	if scale < 0 || scale > maxResultScale {
+		panic(fmt.Sprintf("model: Decimal.Round(%d): scale must be 0 to %d", scale, maxResultScale))
+	}
+	if scale >= d.scale {
+		return fromBig(d.bigUnits(scale), scale)
+	}
+	return fromBig(roundBig(big.NewInt(d.units), d.scale-scale), scale)
+}
+
+// String conforms with Stringer for Decimal values, printing every decimal
+// place held, e.g. "-1234.50".
+func (d Decimal) String() string {
+	u := d.units
+	sign := ""
+	if u < 0 {
+		sign, u = "-", -u
+	}
+	digits := fmt.Sprintf("%0*d", d.scale+1, u)
+	if d.scale == 0 {
+		return sign + digits
+	}
+	n := len(digits) - d.scale
+	return sign + digits[:n] + "." + digits[n:]
+}
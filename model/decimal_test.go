@@ -0,0 +1,172 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Decimal
+		wantErr bool
+	}{
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "1,234.50", wantErr: true},
+		{in: "12a", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "0.1234567890", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+		{in: "0", want: Decimal{0, 0}},
+		{in: "123", want: Decimal{123, 0}},
+		{in: "+123", want: Decimal{123, 0}},
+		{in: "-26.07", want: Decimal{-2607, 2}},
+		{in: "1234.50", want: Decimal{123450, 2}},
+		{in: ".5", want: Decimal{5, 1}},
+		{in: "10.", want: Decimal{10, 0}},
+		{in: "-0.001", want: Decimal{-1, 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			got, err := ParseDecimal(test.in)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Errorf("ParseDecimal(%q)=_, err? %t want? %t (err=%v)", test.in, gotErr, test.wantErr, err)
+			}
+			if got != test.want {
+				t.Errorf("ParseDecimal(%q)=%#v want %#v", test.in, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecimalString(t *testing.T) {
+	tests := []struct {
+		d    Decimal
+		want string
+	}{
+		{Decimal{}, "0"},
+		{Decimal{0, 2}, "0.00"},
+		{Decimal{5, 2}, "0.05"},
+		{Decimal{-5, 2}, "-0.05"},
+		{Decimal{123450, 2}, "1234.50"},
+		{Decimal{-2607, 2}, "-26.07"},
+		{Decimal{42, 0}, "42"},
+	}
+	for _, test := range tests {
+		if got := test.d.String(); got != test.want {
+			t.Errorf("%#v.String()=%q want %q", test.d, got, test.want)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	// 0.1 added ten times is exactly 1, which is not true of float64.
+	var sum Decimal
+	for i := 0; i < 10; i++ {
+		sum = sum.Add(MustParseDecimal("0.10"))
+	}
+	if got, want := sum, MustParseDecimal("1.00"); got != want {
+		t.Errorf("sum of 10 x 0.10=%v want %v", got, want)
+	}
+	if got, want := MustParseDecimal("10").Sub(MustParseDecimal("0.01")), MustParseDecimal("9.99"); got != want {
+		t.Errorf("10 - 0.01=%v want %v", got, want)
+	}
+	if got, want := MustParseDecimal("1.5").Mul(MustParseDecimal("-2.25")), MustParseDecimal("-3.375"); got != want {
+		t.Errorf("1.5 * -2.25=%v want %v", got, want)
+	}
+	if got, want := MustParseDecimal("-10.01").Percent(MustParseDecimal("12.5")), MustParseDecimal("-1.25125"); got != want {
+		t.Errorf("12.5%% of -10.01=%v want %v", got, want)
+	}
+	if got, want := MustParseDecimal("-3.50").Neg(), MustParseDecimal("3.50"); got != want {
+		t.Errorf("-(-3.50)=%v want %v", got, want)
+	}
+	if got, want := MustParseDecimal("-3.50").Abs(), MustParseDecimal("3.50"); got != want {
+		t.Errorf("|-3.50|=%v want %v", got, want)
+	}
+	if !MustParseDecimal("1.0").Equal(MustParseDecimal("1.000")) {
+		t.Errorf("1.0 != 1.000")
+	}
+	if got := MustParseDecimal("1.01").Cmp(MustParseDecimal("1.1")); got != -1 {
+		t.Errorf("1.01 Cmp 1.1=%d want -1", got)
+	}
+	if got := MustParseDecimal("-0.00").Sign(); got != 0 {
+		t.Errorf("-0.00 Sign=%d want 0", got)
+	}
+}
+
+func TestDecimalArithmetic_overflow(t *testing.T) {
+	x := MustParseDecimal("123456.123456789")
+	if got, want := x.Mul(x).String(), "15241414418.97792715"; got != want {
+		t.Errorf("%v * %v=%s want %s", x, x, got, want)
+	}
+	p := MustParseDecimal("1.000000001")
+	for i := 0; i < 4; i++ {
+		p = p.Mul(MustParseDecimal("1.000000001"))
+	}
+	if got, want := p.String(), "1.000000005000000010"; got != want {
+		t.Errorf("1.000000001^5=%s want %s", got, want)
+	}
+	large := MustParseDecimal("9000000000000000000")
+	if got, want := large.Add(MustParseDecimal("0.5")).String(), "9000000000000000001"; got != want {
+		t.Errorf("%v + 0.5=%s want %s", large, got, want)
+	}
+	if got := large.Neg().Cmp(large); got != -1 {
+		t.Errorf("%v Cmp %v=%d want -1", large.Neg(), large, got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%v * %v didn't panic", large, large)
+		}
+	}()
+	large.Mul(large)
+}
+
+func TestNewDecimal(t *testing.T) {
+	if got, want := NewDecimal(1, 9).Add(NewDecimal(1, 0)).String(), "1.000000001"; got != want {
+		t.Errorf("NewDecimal(1, 9) + NewDecimal(1, 0)=%s want %s", got, want)
+	}
+	for _, scale := range []int{-1, 10, 19} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewDecimal(1, %d) didn't panic", scale)
+				}
+			}()
+			NewDecimal(1, scale)
+		}()
+	}
+}
+
+func TestDecimalRound(t *testing.T) {
+	tests := []struct {
+		in    string
+		scale int
+		want  string
+	}{
+		{"1.234", 2, "1.23"},
+		{"1.235", 2, "1.24"},
+		{"-1.235", 2, "-1.24"},
+		{"-1.234", 2, "-1.23"},
+		{"0.005", 2, "0.01"},
+		{"1.5", 0, "2"},
+		{"1.2", 3, "1.200"},
+		{"-0.000000004", 8, "0.00000000"},
+		{"9000000000000000000", 2, "9000000000000000000"},
+	}
+	for _, test := range tests {
+		if got := MustParseDecimal(test.in).Round(test.scale).String(); got != test.want {
+			t.Errorf("%s.Round(%d)=%s want %s", test.in, test.scale, got, test.want)
+		}
+	}
+	for _, scale := range []int{-1, 19} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Round(%d) didn't panic", scale)
+				}
+			}()
+			MustParseDecimal("1.5").Round(scale)
+		}()
+	}
+}
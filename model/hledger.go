@@ -1,14 +1,25 @@
-// Package model contains the types used to model transactions and serialise them in the hledger journal format.
+// Package model contains the types used to model transactions, and read and serialise them in the hledger journal format.
 package model
 
 import (
 	"fmt"
 	"io"
 	"strings"
+	"unicode/utf8"
 )
 
+// postingIndent is the indent of posting lines.
+const postingIndent = "  "
+
 // SerializeHledger writes a text representation in the hledger format of the Transaction to the given Writer.
 func (t *Transaction) SerializeHledger(w io.Writer) error {
+	return t.serializeHledger(w, 0)
+}
+
+// serializeHledger writes t in the hledger format, with the amounts of its
+// postings right-aligned to end at the given column, if it's not 0, or as far
+// past it as t.amountColumn needs.
+func (t *Transaction) serializeHledger(w io.Writer, column int) error {
 	if t == nil {
 		return nil
 	}
@@ -16,9 +27,10 @@ func (t *Transaction) SerializeHledger(w io.Writer) error {
 	if _, err := w.Write([]byte(topLine)); err != nil {
 		return err
 	}
+	column = t.amountColumn(column)
 	for i, p := range t.Postings {
 		last := i == len(t.Postings)-1
-		entLine := fmt.Sprintf("  %s\n", p.postingLine(last))
+		entLine := postingIndent + p.hledgerLine(last, column) + "\n"
 		if _, err := w.Write([]byte(entLine)); err != nil {
 			return err
 		}
@@ -30,10 +42,13 @@ func (t *Transaction) topLine() string {
 	if t == nil {
 		return ""
 	}
-	items := []string{t.Date.Format("2006/01/02")}
+	items := []string{t.dates()}
 	if t.Status != Unknown && t.Status != Unmarked {
 		items = append(items, t.Status.String())
 	}
+	if len(t.Code) > 0 {
+		items = append(items, "("+t.Code+")")
+	}
 	if len(t.Payee) > 0 {
 		items = append(items, t.Payee)
 	}
@@ -43,23 +58,158 @@ func (t *Transaction) topLine() string {
 	if len(t.Description) > 0 {
 		items = append(items, t.Description)
 	}
-	if len(t.Comment) > 0 {
-		items = append(items, "  ; " + t.Comment)
+	comment := hledgerComment(t.Comment, t.Tags)
+	if len(comment) == 0 {
+		return strings.Join(items, " ")
+	}
+	items = append(items, "  ; "+comment[0])
+	return strings.Join(items, " ") + commentLines(comment[1:])
+}
+
+// dates returns the date of t, followed by =date2 if it has a SecondaryDate.
+func (t *Transaction) dates() string {
+	d := t.Date.Format("2006/01/02")
+	if !t.SecondaryDate.IsZero() {
+		d += "=" + t.SecondaryDate.Format("2006/01/02")
+	}
+	return d
+}
+
+// hledgerComment returns the lines of a comment with the given tags appended,
+// in hledger's comma-separated name:value syntax.
+func hledgerComment(comment string, tags []Tag) []string {
+	var lines []string
+	if comment != "" {
+		lines = strings.Split(comment, "\n")
+	}
+	if len(tags) == 0 {
+		return lines
+	}
+	var ts []string
+	for _, tag := range tags {
+		ts = append(ts, tag.String())
+	}
+	if len(lines) == 0 {
+		return []string{strings.Join(ts, ", ")}
+	}
+	lines[len(lines)-1] += ", " + strings.Join(ts, ", ")
+	return lines
+}
+
+// withComment returns line followed by the comment lines given: the first on
+// the same line, and any others on indented lines of their own.
+func withComment(line string, comment []string) string {
+	if len(comment) == 0 {
+		return line
+	}
+	return line + "  ; " + comment[0] + commentLines(comment[1:])
+}
+
+// commentLines returns the comment lines given, each on an indented line of
+// its own.
+func commentLines(comment []string) string {
+	var s string
+	for _, c := range comment {
+		s += "\n    ; " + c
+	}
+	return s
+}
+
+// SerializeHledger writes an hledger account directive for the AccountDeclaration
+// to the given Writer, with a type: tag if the type is known.
+func (a *AccountDeclaration) SerializeHledger(w io.Writer) error {
+	if a == nil {
+		return nil
+	}
+	line := "account " + a.Account.hledgerName()
+	if a.Type != UntypedAccount {
+		line += "  ; type: " + a.Type.String()
 	}
-	return strings.Join(items, " ")
+	_, err := w.Write([]byte(line + "\n"))
+	return err
 }
 
-func (p *Posting) postingLine(last bool) string {
+// hledgerName returns the account name as written in hledger journals.
+func (a Account) hledgerName() string {
 	ac := ""
-	for i, a := range p.Account {
-		ac += strings.ToLower(strings.Replace(a, " ", "_", -1))
-		if i < len(p.Account) - 1 {
+	for i, n := range a {
+		ac += strings.ToLower(strings.Replace(n, " ", "_", -1))
+		if i < len(a)-1 {
 			ac += ":"
 		}
 	}
-	if last {
+	return ac
+}
+
+// amountColumn returns the column that the amounts of t's postings are
+// right-aligned to end at: min, unless that's too near to leave two spaces
+// between the widest account and the widest amount, as `hledger print` lays
+// them out.  It's 0, for no alignment, if min is.
+func (t *Transaction) amountColumn(min int) int {
+	if min == 0 {
+		return 0
+	}
+	var account, amount int
+	for i := range t.Postings {
+		p := &t.Postings[i]
+		if n := utf8.RuneCountInString(p.accountField()); n > account {
+			account = n
+		}
+		if n := utf8.RuneCountInString(p.Amount.String()); n > amount && !p.elided(i == len(t.Postings)-1) {
+			amount = n
+		}
+	}
+	if col := len(postingIndent) + account + 2 + amount; col > min {
+		return col
+	}
+	return min
+}
+
+// hledgerLine returns the posting line for p, with its comment and tags.
+func (p *Posting) hledgerLine(last bool, column int) string {
+	return withComment(p.postingLine(last, column), hledgerComment(p.Comment, p.Tags))
+}
+
+// postingLine returns the posting line for p, without its comment: any status
+// mark, the account, and unless it's the last posting of its Transaction and
+// can be elided, the amount.  If column isn't 0 the amount is right-aligned to
+// end at that column of the indented line, as far as the account allows.
+func (p *Posting) postingLine(last bool, column int) string {
+	ac := p.accountField()
+	if p.elided(last) {
 		return ac
 	}
-	// TODO(phad): optional status at start.
-	return fmt.Sprintf("%s  %f", ac, p.Amount)
+	amount := p.Amount.String()
+	pad := column - len(postingIndent) - utf8.RuneCountInString(ac) - utf8.RuneCountInString(amount)
+	if pad < 2 {
+		pad = 2
+	}
+	line := ac + strings.Repeat(" ", pad) + amount
+	if p.Cost != nil {
+		at := "@"
+		if p.TotalCost {
+			at = "@@"
+		}
+		line += fmt.Sprintf(" %s %s", at, p.Cost)
+	}
+	if p.Assertion != nil {
+		// The amount is never elided here, which would make this a balance
+		// assignment rather than an assertion.
+		line += " = " + p.Assertion.String()
+	}
+	return line
+}
+
+// accountField returns the account of p, after any status mark.
+func (p *Posting) accountField() string {
+	if p.Status != Unknown && p.Status != Unmarked {
+		return p.Status.String() + " " + p.Account.hledgerName()
+	}
+	return p.Account.hledgerName()
+}
+
+// elided reports whether the amount of p is left out, as it can be for the
+// last posting of a Transaction unless it has a balance assertion.
+func (p *Posting) elided(last bool) bool {
+	return last && p.Assertion == nil
 }
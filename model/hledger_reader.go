@@ -0,0 +1,339 @@
+package model
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ParseHledger reads the account declarations and transactions of an hledger
+// journal.  It understands the journal format that SerializeHledger writes,
+// along with status marks, (codes), comments, tags, secondary dates, balance
+// assertions and elided amounts.  Tags are taken out of the comments they are
+// written in.  Other directives, such as commodity and include, are skipped.
+//
+// Transactions and postings with no status mark are Unmarked.  A description
+// with no | separating the payee from a note is read as the Payee.  The amount
+// of the posting that has none is inferred from the others, which must then be
+// in a single commodity.
+func ParseHledger(r io.Reader) (*Journal, error) {
+	p := &hledgerParser{scanner: bufio.NewScanner(r), journal: &Journal{}}
+	for p.scanner.Scan() {
+		p.lineNum++
+		if err := p.line(strings.TrimRight(p.scanner.Text(), "\r")); err != nil {
+			return nil, fmt.Errorf("hledger: line %d: %v", p.lineNum, err)
+		}
+	}
+	if err := p.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("hledger: scanner error at line %d: %v", p.lineNum, err)
+	}
+	if err := p.finish(); err != nil {
+		return nil, fmt.Errorf("hledger: line %d: %v", p.txnLine, err)
+	}
+	return p.journal, nil
+}
+
+// hledgerParser contains the scan state for an hledger journal.
+type hledgerParser struct {
+	scanner   *bufio.Scanner
+	journal   *Journal
+	lineNum   int
+	txn       *Transaction // The Transaction in progress, if any.
+	txnLine   int          // The line number that txn started on.
+	elided    int          // The index of the posting of txn with no amount, or -1.
+	skipping  bool         // Whether indented lines belong to a skipped directive.
+	inComment bool         // Whether lines are in a comment block.
+}
+
+// line processes a single line of the journal.
+func (p *hledgerParser) line(l string) error {
+	if p.inComment {
+		p.inComment = strings.TrimSpace(l) != "end comment"
+		return nil
+	}
+	if strings.TrimSpace(l) == "" {
+		return p.finish()
+	}
+	if l[0] == ' ' || l[0] == '\t' {
+		switch {
+		case p.txn != nil:
+			return p.subLine(strings.TrimSpace(l))
+		case p.skipping:
+			return nil
+		}
+		return fmt.Errorf("unexpected indented line %q", l)
+	}
+	if err := p.finish(); err != nil {
+		return fmt.Errorf("transaction from line %d: %v", p.txnLine, err)
+	}
+	p.skipping = false
+	switch {
+	case strings.ContainsRune(";#*", rune(l[0])):
+		// Comment line.
+	case l[0] >= '0' && l[0] <= '9':
+		t, err := parseTopLine(l)
+		if err != nil {
+			return err
+		}
+		p.txn, p.txnLine, p.elided = t, p.lineNum, -1
+	case strings.HasPrefix(l, "account "):
+		p.journal.Accounts = append(p.journal.Accounts, parseAccountDirective(l))
+		p.skipping = true
+	case strings.TrimSpace(l) == "comment":
+		p.inComment = true
+	default:
+		// Another directive, which may be followed by indented lines of its own.
+		p.skipping = true
+	}
+	return nil
+}
+
+// subLine processes a trimmed, indented line that follows the top line of a
+// Transaction: either a comment or a posting.
+func (p *hledgerParser) subLine(l string) error {
+	t := p.txn
+	if strings.HasPrefix(l, ";") {
+		c, tags := parseTags(strings.TrimSpace(l[1:]))
+		if n := len(t.Postings); n > 0 {
+			t.Postings[n-1].Comment = joinComment(t.Postings[n-1].Comment, c)
+			t.Postings[n-1].Tags = append(t.Postings[n-1].Tags, tags...)
+		} else {
+			t.Comment = joinComment(t.Comment, c)
+			t.Tags = append(t.Tags, tags...)
+		}
+		return nil
+	}
+	ps, elided, err := parsePosting(l)
+	if err != nil {
+		return err
+	}
+	if elided {
+		if p.elided >= 0 {
+			return fmt.Errorf("more than one posting with no amount")
+		}
+		p.elided = len(t.Postings)
+	}
+	t.Postings = append(t.Postings, ps)
+	return nil
+}
+
+// finish completes the Transaction in progress, if any, inferring the amount
+// of any posting that has none and checking that its postings balance.
+func (p *hledgerParser) finish() error {
+	t := p.txn
+	if t == nil {
+		return nil
+	}
+	p.txn = nil
+	sums := map[string]Decimal{}
+	commodities := map[string]Commodity{}
+	for i, ps := range t.Postings {
+		if i == p.elided {
+			continue
+		}
+		w := ps.Weight()
+		sums[w.Commodity.Symbol] = sums[w.Commodity.Symbol].Add(w.Quantity)
+		commodities[w.Commodity.Symbol] = w.Commodity
+	}
+	var unbalanced []string
+	for sym, sum := range sums {
+		if !sum.IsZero() {
+			unbalanced = append(unbalanced, sym)
+		}
+	}
+	switch {
+	case p.elided >= 0 && len(unbalanced) > 1:
+		return fmt.Errorf("can't infer the amount of %s from postings in more than one commodity", t.Postings[p.elided].Account.hledgerName())
+	case p.elided >= 0 && len(unbalanced) == 1:
+		sym := unbalanced[0]
+		t.Postings[p.elided].Amount = Amount{Quantity: sums[sym].Neg(), Commodity: commodities[sym]}
+	case p.elided >= 0:
+		for _, c := range commodities {
+			t.Postings[p.elided].Amount.Commodity = c
+		}
+	case len(unbalanced) == 2:
+		// Postings in two commodities balance at an implied price.
+	case len(unbalanced) > 0:
+		return fmt.Errorf("postings don't balance, off by %s", Amount{Quantity: sums[unbalanced[0]], Commodity: commodities[unbalanced[0]]})
+	}
+	p.journal.Transactions = append(p.journal.Transactions, t)
+	return nil
+}
+
+// joinComment adds the line l to the comment c.
+func joinComment(c, l string) string {
+	switch {
+	case l == "":
+		return c
+	case c == "":
+		return l
+	}
+	return c + "\n" + l
+}
+
+// parseTopLine parses the first line of a Transaction:
+// date[=date2] [status] [(code)] [payee |] [description] [; comment]
+func parseTopLine(l string) (*Transaction, error) {
+	t := &Transaction{Status: Unmarked}
+	l, comment := splitComment(l)
+	t.Comment, t.Tags = parseTags(comment)
+	dates, rest := l, ""
+	if i := strings.IndexAny(l, " \t"); i >= 0 {
+		dates, rest = l[:i], strings.TrimSpace(l[i:])
+	}
+	var err error
+	ds := strings.SplitN(dates, "=", 2)
+	if t.Date, err = parseHledgerDate(ds[0]); err != nil {
+		return nil, err
+	}
+	if len(ds) > 1 {
+		if t.SecondaryDate, err = parseHledgerDate(ds[1]); err != nil {
+			return nil, err
+		}
+	}
+	t.Status, rest = parseStatus(rest)
+	if strings.HasPrefix(rest, "(") {
+		i := strings.IndexByte(rest, ')')
+		if i < 0 {
+			return nil, fmt.Errorf("unterminated code in %q", l)
+		}
+		t.Code, rest = rest[1:i], strings.TrimSpace(rest[i+1:])
+	}
+	if i := strings.IndexByte(rest, '|'); i >= 0 {
+		t.Payee, t.Description = strings.TrimSpace(rest[:i]), strings.TrimSpace(rest[i+1:])
+	} else {
+		t.Payee = rest
+	}
+	return t, nil
+}
+
+// parsePosting parses a trimmed posting line:
+// [status] account[  amount [@ cost|@@ cost] [= assertion]] [; comment]
+// It reports whether the amount was elided.
+func parsePosting(l string) (Posting, bool, error) {
+	var p Posting
+	l, comment := splitComment(l)
+	p.Comment, p.Tags = parseTags(comment)
+	p.Status, l = parseStatus(l)
+	account, rest := l, ""
+	if i := strings.Index(l, "  "); i >= 0 {
+		account, rest = l[:i], strings.TrimSpace(l[i:])
+	}
+	if i := strings.IndexByte(account, '\t'); i >= 0 {
+		account, rest = account[:i], strings.TrimSpace(l[i:])
+	}
+	p.Account = Account(strings.Split(strings.TrimSpace(account), ":"))
+	if i := strings.IndexByte(rest, '='); i >= 0 {
+		a, err := ParseAmount(strings.TrimLeft(rest[i:], "=*"))
+		if err != nil {
+			return p, false, err
+		}
+		p.Assertion, rest = &a, strings.TrimSpace(rest[:i])
+	}
+	if rest == "" {
+		if p.Assertion != nil {
+			return p, false, fmt.Errorf("balance assignments are not supported")
+		}
+		return p, true, nil
+	}
+	if i := strings.IndexByte(rest, '@'); i >= 0 {
+		p.TotalCost = strings.HasPrefix(rest[i:], "@@")
+		c, err := ParseAmount(strings.TrimLeft(rest[i:], "@"))
+		if err != nil {
+			return p, false, err
+		}
+		p.Cost, rest = &c, rest[:i]
+	}
+	var err error
+	p.Amount, err = ParseAmount(rest)
+	return p, false, err
+}
+
+// splitComment returns l up to any ; comment, and the comment.
+func splitComment(l string) (string, string) {
+	if i := strings.IndexByte(l, ';'); i >= 0 {
+		return strings.TrimSpace(l[:i]), strings.TrimSpace(l[i+1:])
+	}
+	return strings.TrimSpace(l), ""
+}
+
+// parseTags returns the Tags written in the comment c, and the rest of its
+// text.  As in hledger, a tag is a word followed by a colon, and its value runs
+// to the next comma or the end of the comment.
+func parseTags(c string) (string, []Tag) {
+	var text []string
+	var tags []Tag
+	for _, f := range strings.Split(c, ",") {
+		f = strings.TrimSpace(f)
+		i := strings.IndexByte(f, ':')
+		if i < 0 {
+			text = append(text, f)
+			continue
+		}
+		j := strings.LastIndexAny(f[:i], " \t") + 1
+		if j == i {
+			text = append(text, f)
+			continue
+		}
+		if pre := strings.TrimSpace(f[:j]); pre != "" {
+			text = append(text, pre)
+		}
+		tags = append(tags, Tag{Name: f[j:i], Value: strings.TrimSpace(f[i+1:])})
+	}
+	return strings.Join(text, ", "), tags
+}
+
+// parseStatus returns the Status given by any status mark at the start of s,
+// and the rest of s.
+func parseStatus(s string) (Status, string) {
+	switch {
+	case strings.HasPrefix(s, "*"):
+		return Cleared, strings.TrimSpace(s[1:])
+	case strings.HasPrefix(s, "!"):
+		return Pending, strings.TrimSpace(s[1:])
+	}
+	return Unmarked, s
+}
+
+// parseHledgerDate parses a date in any of hledger's full date formats, eg.
+// 2017/01/12, 2017-01-12 or 2017.1.12.
+func parseHledgerDate(d string) (time.Time, error) {
+	d = strings.NewReplacer("-", "/", ".", "/").Replace(d)
+	return time.Parse("2006/1/2", d)
+}
+
+// parseAccountDirective parses an account directive, along with any type: tag
+// in its comment.
+func parseAccountDirective(l string) *AccountDeclaration {
+	l, comment := splitComment(strings.TrimPrefix(l, "account "))
+	if i := strings.Index(l, "  "); i >= 0 {
+		l = l[:i]
+	}
+	a := &AccountDeclaration{Account: Account(strings.Split(l, ":"))}
+	if i := strings.Index(comment, "type:"); i >= 0 {
+		typ := strings.TrimSpace(strings.SplitN(comment[i+len("type:"):], ",", 2)[0])
+		a.Type = parseAccountType(typ)
+	}
+	return a
+}
+
+// parseAccountType returns the AccountType with the given name, or its one
+// letter code.
+func parseAccountType(s string) AccountType {
+	for t := Asset; t <= Cash; t++ {
+		if name := t.String(); strings.EqualFold(s, name) || strings.EqualFold(s, typeCode(t)) {
+			return t
+		}
+	}
+	return UntypedAccount
+}
+
+// typeCode returns the one letter code hledger accepts for the AccountType.
+func typeCode(t AccountType) string {
+	if t == Expense {
+		return "X"
+	}
+	return t.String()[:1]
+}
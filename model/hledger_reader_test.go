@@ -0,0 +1,190 @@
+package model
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHledger(t *testing.T) {
+	pound := NewCommodity("£")
+	gbp := func(s string) Amount { return Amount{MustParseDecimal(s), pound} }
+	tests := []struct {
+		desc    string
+		journal string
+		want    *Journal
+		wantErr bool
+	}{
+		{
+			desc: "empty",
+			want: &Journal{},
+		},
+		{
+			desc: "directives and comments",
+			journal: `; A journal
+commodity £1,000.00
+  format £1,000.00
+account assets:current  ; type: Cash
+account liabilities:visa  ; type: L, note: credit card
+account equity:opening_balances
+comment
+2017/01/12 Not a transaction
+end comment
+`,
+			want: &Journal{Accounts: []*AccountDeclaration{
+				{Account: Account{"assets", "current"}, Type: Cash},
+				{Account: Account{"liabilities", "visa"}, Type: Liability},
+				{Account: Account{"equity", "opening_balances"}},
+			}},
+		},
+		{
+			desc: "status, code, payee, note and comments",
+			journal: `2017/01/12=2017/01/14 * (123) Dave | Groceries  ; shop:tesco
+    ; second line
+    ! expenses:food  £10.00  ; posting comment, receipt:
+    ; milk, bread,  aisle: 3
+    assets:current    -£10.00 = £90.00
+`,
+			want: &Journal{Transactions: []*Transaction{{
+				Date:          d1,
+				SecondaryDate: time.Date(2017, time.January, 14, 0, 0, 0, 0, time.UTC),
+				Status:        Cleared,
+				Code:          "123",
+				Payee:         "Dave",
+				Description:   "Groceries",
+				Comment:       "second line",
+				Tags:          []Tag{{Name: "shop", Value: "tesco"}},
+				Postings: []Posting{
+					{Status: Pending, Account: Account{"expenses", "food"}, Amount: gbp("10.00"), Comment: "posting comment\nmilk, bread", Tags: []Tag{{Name: "receipt"}, {Name: "aisle", Value: "3"}}},
+					{Status: Unmarked, Account: Account{"assets", "current"}, Amount: gbp("-10.00"), Assertion: &Amount{MustParseDecimal("90.00"), pound}},
+				},
+			}}},
+		},
+		{
+			desc: "elided amount inferred from costs",
+			journal: `2017-01-12 Buy
+	assets:isa	10 VWRL @ £50.50
+	expenses:fees  £5.00
+	assets:isa
+`,
+			want: &Journal{Transactions: []*Transaction{{
+				Date:   d1,
+				Status: Unmarked,
+				Payee:  "Buy",
+				Postings: []Posting{
+					{Status: Unmarked, Account: Account{"assets", "isa"}, Amount: Amount{MustParseDecimal("10"), NewCommodity("VWRL")}, Cost: &Amount{MustParseDecimal("50.50"), pound}},
+					{Status: Unmarked, Account: Account{"expenses", "fees"}, Amount: gbp("5.00")},
+					{Status: Unmarked, Account: Account{"assets", "isa"}, Amount: gbp("-510.00")},
+				},
+			}}},
+		},
+		{
+			desc: "two commodities balance at an implied price",
+			journal: `2017.1.12
+  assets:euro  1,000 EUR
+  assets:current  -£1,086.00
+`,
+			want: &Journal{Transactions: []*Transaction{{
+				Date:   d1,
+				Status: Unmarked,
+				Postings: []Posting{
+					{Status: Unmarked, Account: Account{"assets", "euro"}, Amount: Amount{MustParseDecimal("1000"), NewCommodity("EUR")}},
+					{Status: Unmarked, Account: Account{"assets", "current"}, Amount: gbp("-1086.00")},
+				},
+			}}},
+		},
+		{
+			desc:    "unbalanced",
+			journal: "2017/01/12\n  expenses:food  £10.00\n  assets:current  -£9.00\n",
+			wantErr: true,
+		},
+		{
+			desc:    "two elided amounts",
+			journal: "2017/01/12\n  expenses:food\n  assets:current\n",
+			wantErr: true,
+		},
+		{
+			desc:    "balance assignment",
+			journal: "2017/01/12\n  expenses:food  £10.00\n  assets:current  = £90.00\n",
+			wantErr: true,
+		},
+		{
+			desc:    "bad date",
+			journal: "2017/13/12\n  expenses:food  £10.00\n  assets:current\n",
+			wantErr: true,
+		},
+		{
+			desc:    "posting outside a transaction",
+			journal: "\n  expenses:food  £10.00\n",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := ParseHledger(strings.NewReader(test.journal))
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("ParseHledger()=_, err? %t want? %t (err=%v)", gotErr, test.wantErr, err)
+			}
+			if err == nil && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseHledger()=%+v want %+v", got, test.want)
+				for i := range got.Transactions {
+					t.Logf("got transaction %d: %+v", i, got.Transactions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseHledger_roundTrip(t *testing.T) {
+	var want bytes.Buffer
+	decl := &AccountDeclaration{Account: Account{"assets", "Current"}, Type: Cash}
+	if err := decl.SerializeHledger(&want); err != nil {
+		t.Fatalf("SerializeHledger() error: %v", err)
+	}
+	for _, txn := range []*Transaction{
+		{
+			Date:    d1,
+			Status:  Cleared,
+			Payee:   "Opening Balance",
+			Comment: `transfer-to:"Savings"`,
+			Postings: []Posting{
+				{Account: Account{"equity", "opening balances"}, Amount: Amount{MustParseDecimal("-1234.56"), NewCommodity("£")}},
+				{Account: Account{"assets", "Current"}, Amount: Amount{MustParseDecimal("1234.56"), NewCommodity("£")}},
+			},
+		},
+		{
+			Date:        d1,
+			Payee:       "Dave",
+			Description: "Shares",
+			Postings: []Posting{
+				{Account: Account{"assets", "ISA"}, Amount: Amount{MustParseDecimal("-2"), NewCommodity("VUSA")}, Cost: &Amount{MustParseDecimal("70.01"), NewCommodity("£")}, TotalCost: true},
+				{Status: Cleared, Account: Account{"assets", "Current"}, Amount: Amount{MustParseDecimal("70.01"), NewCommodity("£")}, Assertion: &Amount{MustParseDecimal("1304.57"), NewCommodity("£")}, Comment: "sold\nat market", Tags: []Tag{{Name: "broker", Value: "HL"}}},
+			},
+		},
+	} {
+		if err := txn.SerializeHledger(&want); err != nil {
+			t.Fatalf("SerializeHledger() error: %v", err)
+		}
+	}
+
+	j, err := ParseHledger(bytes.NewReader(want.Bytes()))
+	if err != nil {
+		t.Fatalf("ParseHledger() error: %v", err)
+	}
+	var got bytes.Buffer
+	for _, a := range j.Accounts {
+		if err := a.SerializeHledger(&got); err != nil {
+			t.Fatalf("SerializeHledger() error: %v", err)
+		}
+	}
+	for _, txn := range j.Transactions {
+		if err := txn.SerializeHledger(&got); err != nil {
+			t.Fatalf("SerializeHledger() error: %v", err)
+		}
+	}
+	if got.String() != want.String() {
+		t.Errorf("SerializeHledger() of parsed journal=%q want %q", got.String(), want.String())
+	}
+}
@@ -52,6 +52,26 @@ func TestSerializeHledger_topLine(t *testing.T) {
 			txn:  &Transaction{Date: d1, Payee: "Dave", Description: "Groceries", Status: Cleared},
 			want: "2017/01/12 * Dave | Groceries",
 		},
+		{
+			desc: "txn with comment lines and tags",
+			txn:  &Transaction{Date: d1, Payee: "Dave", Comment: "weekly\nshop", Tags: []Tag{{Name: "shop", Value: "tesco"}, {Name: "online"}}},
+			want: "2017/01/12 Dave   ; weekly\n    ; shop, shop:tesco, online:",
+		},
+		{
+			desc: "txn with secondary date",
+			txn:  &Transaction{Date: d1, SecondaryDate: time.Date(2017, time.January, 14, 0, 0, 0, 0, time.UTC), Payee: "Dave"},
+			want: "2017/01/12=2017/01/14 Dave",
+		},
+		{
+			desc: "txn with code",
+			txn:  &Transaction{Date: d1, Code: "DD", Payee: "Dave", Status: Cleared},
+			want: "2017/01/12 * (DD) Dave",
+		},
+		{
+			desc: "txn with tags only",
+			txn:  &Transaction{Date: d1, Payee: "Dave", Tags: []Tag{{Name: "shop", Value: "tesco"}}},
+			want: "2017/01/12 Dave   ; shop:tesco",
+		},
 	}
 
 	for _, test := range tests {
@@ -71,6 +91,64 @@ func TestSerializeHledger(t *testing.T) {
 		wantErr bool
 	}{
 		{desc: "nil Transaction, does nothing"},
+		{
+			desc: "amounts keep their decimal places, last posting elided",
+			txn: &Transaction{
+				Date:  d1,
+				Payee: "Dave",
+				Postings: []Posting{
+					{Account: Account{"expenses", "Food"}, Amount: Amount{Quantity: MustParseDecimal("1234.50")}},
+					{Account: Account{"expenses", "Drink"}, Amount: Amount{Quantity: MustParseDecimal("0.5")}},
+					{Account: Account{"assets", "Current Account"}, Amount: Amount{Quantity: MustParseDecimal("-1235.00")}},
+				},
+			},
+			want: "\n2017/01/12 Dave\n  expenses:food  1234.50\n  expenses:drink  0.5\n  assets:current_account\n",
+		},
+		{
+			desc: "amounts with commodity symbols",
+			txn: &Transaction{
+				Date: d1,
+				Postings: []Posting{
+					{Account: Account{"expenses", "Travel"}, Amount: Amount{MustParseDecimal("100.00"), NewCommodity("EUR")}},
+					{Account: Account{"assets", "Euro Account"}, Amount: Amount{MustParseDecimal("-100.00"), NewCommodity("EUR")}},
+				},
+			},
+			want: "\n2017/01/12\n  expenses:travel  100.00 EUR\n  assets:euro_account\n",
+		},
+		{
+			desc: "unit and total costs",
+			txn: &Transaction{
+				Date: d1,
+				Postings: []Posting{
+					{Account: Account{"assets", "ISA"}, Amount: Amount{MustParseDecimal("10"), NewCommodity("VWRL")}, Cost: &Amount{MustParseDecimal("50.00"), NewCommodity("£")}},
+					{Account: Account{"assets", "ISA"}, Amount: Amount{MustParseDecimal("-2"), NewCommodity("VUSA")}, Cost: &Amount{MustParseDecimal("70.01"), NewCommodity("£")}, TotalCost: true},
+					{Account: Account{"assets", "ISA"}, Amount: Amount{MustParseDecimal("-429.99"), NewCommodity("£")}},
+				},
+			},
+			want: "\n2017/01/12\n  assets:isa  10 VWRL @ £50.00\n  assets:isa  -2 VUSA @@ £70.01\n  assets:isa\n",
+		},
+		{
+			desc: "balance assertion keeps last amount",
+			txn: &Transaction{
+				Date: d1,
+				Postings: []Posting{
+					{Account: Account{"expenses", "Food"}, Amount: Amount{MustParseDecimal("5.00"), NewCommodity("£")}},
+					{Account: Account{"assets", "Current"}, Amount: Amount{MustParseDecimal("-5.00"), NewCommodity("£")}, Assertion: &Amount{MustParseDecimal("95.00"), NewCommodity("£")}},
+				},
+			},
+			want: "\n2017/01/12\n  expenses:food  £5.00\n  assets:current  -£5.00 = £95.00\n",
+		},
+		{
+			desc: "posting status, comments and tags",
+			txn: &Transaction{
+				Date: d1,
+				Postings: []Posting{
+					{Status: Cleared, Account: Account{"expenses", "Food"}, Amount: Amount{MustParseDecimal("5.00"), NewCommodity("£")}, Comment: "lunch"},
+					{Status: Pending, Account: Account{"assets", "Current"}, Amount: Amount{MustParseDecimal("-5.00"), NewCommodity("£")}, Tags: []Tag{{Name: "card", Value: "1234"}}},
+				},
+			},
+			want: "\n2017/01/12\n  * expenses:food  £5.00  ; lunch\n  ! assets:current  ; card:1234\n",
+		},
 	}
 
 	for _, test := range tests {
@@ -86,3 +164,74 @@ func TestSerializeHledger(t *testing.T) {
 		})
 	}
 }
+
+func TestPostingLine_aligned(t *testing.T) {
+	pound := NewCommodity("£")
+	tests := []struct {
+		desc    string
+		posting Posting
+		last    bool
+		want    string
+	}{
+		{
+			desc:    "amount right-aligned",
+			posting: Posting{Account: Account{"expenses", "Food"}, Amount: Amount{MustParseDecimal("5.00"), pound}},
+			want:    "expenses:food          £5.00",
+		},
+		{
+			desc:    "cost and assertion follow the aligned amount",
+			posting: Posting{Account: Account{"assets", "ISA"}, Amount: Amount{MustParseDecimal("10"), NewCommodity("VWRL")}, Cost: &Amount{MustParseDecimal("50.00"), pound}, Assertion: &Amount{MustParseDecimal("20"), NewCommodity("VWRL")}},
+			last:    true,
+			want:    "assets:isa           10 VWRL @ £50.00 = 20 VWRL",
+		},
+		{
+			desc:    "long account keeps two spaces",
+			posting: Posting{Account: Account{"expenses", "Household", "Repairs and Maintenance"}, Amount: Amount{MustParseDecimal("-1234.50"), pound}},
+			want:    "expenses:household:repairs_and_maintenance  -£1234.50",
+		},
+		{
+			desc:    "elided amount",
+			posting: Posting{Account: Account{"assets", "Current"}, Amount: Amount{MustParseDecimal("-5.00"), pound}},
+			last:    true,
+			want:    "assets:current",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := test.posting.postingLine(test.last, 30); got != test.want {
+				t.Errorf("postingLine()=%q want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSerializeHledger_accountDeclaration(t *testing.T) {
+	tests := []struct {
+		desc string
+		decl *AccountDeclaration
+		want string
+	}{
+		{desc: "nil declaration, does nothing"},
+		{
+			desc: "untyped",
+			decl: &AccountDeclaration{Account: Account{"assets", "Premium Bonds"}},
+			want: "account assets:premium_bonds\n",
+		},
+		{
+			desc: "typed",
+			decl: &AccountDeclaration{Account: Account{"liabilities", "Orange VISA"}, Type: Liability},
+			want: "account liabilities:orange_visa  ; type: Liability\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := test.decl.SerializeHledger(&got); err != nil {
+				t.Errorf("SerializeHledger() error: %v", err)
+			}
+			if got.String() != test.want {
+				t.Errorf("SerializeHledger()=%q want %q", got.String(), test.want)
+			}
+		})
+	}
+}
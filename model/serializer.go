@@ -0,0 +1,132 @@
+package model
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Serializer writes a Journal in the journal format of a plain text accounting
+// tool.
+type Serializer interface {
+	Serialize(w io.Writer, j *Journal) error
+}
+
+// NewSerializer returns the Serializer for the named format: "hledger",
+// "ledger" or "beancount".
+func NewSerializer(format string) (Serializer, error) {
+	switch format {
+	case "hledger":
+		return &HledgerSerializer{}, nil
+	case "ledger":
+		return &LedgerSerializer{}, nil
+	case "beancount":
+		return &BeancountSerializer{}, nil
+	}
+	return nil, fmt.Errorf("unknown journal format %q: want \"hledger\", \"ledger\" or \"beancount\"", format)
+}
+
+// HledgerSerializer writes journals in the hledger format.
+type HledgerSerializer struct {
+	// AmountColumn is the column that posting amounts are right-aligned to
+	// end at, so that they line up across the journal.  A transaction whose
+	// accounts and amounts are too wide for it is aligned further right, as
+	// `hledger print` lays them out.  If it's 0, each amount follows its
+	// account after two spaces.
+	AmountColumn int
+}
+
+// Serialize writes an account directive for each of j's Accounts, followed by
+// its Transactions.
+func (s *HledgerSerializer) Serialize(w io.Writer, j *Journal) error {
+	for _, a := range j.Accounts {
+		if err := a.SerializeHledger(w); err != nil {
+			return err
+		}
+	}
+	for _, t := range j.Transactions {
+		if err := t.serializeHledger(w, s.AmountColumn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LedgerSerializer writes journals in the ledger-cli format.  This is close to
+// the hledger format, but ledger has no separate payee and note, so a
+// Transaction's Description is written as a comment when it has a Payee, tags
+// are written as metadata, and account types are not declared.
+type LedgerSerializer struct {
+	// AmountColumn is the column that posting amounts are right-aligned to
+	// end at, as for HledgerSerializer.
+	AmountColumn int
+}
+
+// Serialize writes an account directive for each of j's Accounts, followed by
+// its Transactions.
+func (s *LedgerSerializer) Serialize(w io.Writer, j *Journal) error {
+	for _, a := range j.Accounts {
+		if _, err := fmt.Fprintf(w, "account %s\n", a.Account.hledgerName()); err != nil {
+			return err
+		}
+	}
+	for _, t := range j.Transactions {
+		if err := s.transaction(w, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LedgerSerializer) transaction(w io.Writer, t *Transaction) error {
+	items := []string{t.dates()}
+	if t.Status != Unknown && t.Status != Unmarked {
+		items = append(items, t.Status.String())
+	}
+	if len(t.Code) > 0 {
+		items = append(items, "("+t.Code+")")
+	}
+	var note string
+	switch {
+	case len(t.Payee) > 0:
+		items = append(items, t.Payee)
+		note = t.Description
+	case len(t.Description) > 0:
+		items = append(items, t.Description)
+	}
+	comment := ledgerComment(t.Comment, t.Tags)
+	if len(comment) > 0 {
+		items = append(items, "  ; "+comment[0])
+	}
+	lines := []string{"", strings.Join(items, " ")}
+	if len(comment) > 1 {
+		lines[1] += commentLines(comment[1:])
+	}
+	if len(note) > 0 {
+		lines = append(lines, "  ; "+note)
+	}
+	column := t.amountColumn(s.AmountColumn)
+	for i, p := range t.Postings {
+		lines = append(lines, postingIndent+withComment(p.postingLine(i == len(t.Postings)-1, column), ledgerComment(p.Comment, p.Tags)))
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// ledgerComment returns the lines of a comment followed by a line for each of
+// the given tags, written as ledger metadata, "name: value", or as a ledger
+// tag, ":name:", if it has no value.
+func ledgerComment(comment string, tags []Tag) []string {
+	var lines []string
+	if comment != "" {
+		lines = strings.Split(comment, "\n")
+	}
+	for _, tag := range tags {
+		if tag.Value == "" {
+			lines = append(lines, ":"+tag.Name+":")
+		} else {
+			lines = append(lines, tag.Name+": "+tag.Value)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var testJournal = &Journal{
+	Accounts: []*AccountDeclaration{
+		{Account: Account{"assets", "Current"}, Type: Cash},
+		{Account: Account{"expenses", "Food"}, Type: Expense},
+	},
+	Transactions: []*Transaction{
+		{
+			Date:        d1,
+			Status:      Cleared,
+			Code:        "123",
+			Payee:       "Dave",
+			Description: "Groceries",
+			Comment:     "weekly shop",
+			Tags:        []Tag{{Name: "shop", Value: "tesco"}},
+			Postings: []Posting{
+				{Status: Pending, Account: Account{"expenses", "Food"}, Amount: Amount{MustParseDecimal("10.00"), NewCommodity("£")}, Comment: "milk\nbread", Tags: []Tag{{Name: "receipt"}}},
+				{Account: Account{"assets", "Current"}, Amount: Amount{MustParseDecimal("-10.00"), NewCommodity("£")}},
+			},
+		},
+	},
+}
+
+func TestNewSerializer(t *testing.T) {
+	for _, format := range []string{"hledger", "ledger", "beancount"} {
+		if _, err := NewSerializer(format); err != nil {
+			t.Errorf("NewSerializer(%q) error: %v", format, err)
+		}
+	}
+	if _, err := NewSerializer("gnucash"); err == nil {
+		t.Errorf("NewSerializer(%q) got no error", "gnucash")
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	tests := []struct {
+		desc string
+		s    Serializer
+		want string
+	}{
+		{
+			desc: "hledger",
+			s:    &HledgerSerializer{},
+			want: "account assets:current  ; type: Cash\n" +
+				"account expenses:food  ; type: Expense\n" +
+				"\n2017/01/12 * (123) Dave | Groceries   ; weekly shop, shop:tesco\n" +
+				"  ! expenses:food  £10.00  ; milk\n" +
+				"    ; bread, receipt:\n" +
+				"  assets:current\n",
+		},
+		{
+			desc: "hledger, aligned",
+			s:    &HledgerSerializer{AmountColumn: 30},
+			want: "account assets:current  ; type: Cash\n" +
+				"account expenses:food  ; type: Expense\n" +
+				"\n2017/01/12 * (123) Dave | Groceries   ; weekly shop, shop:tesco\n" +
+				"  ! expenses:food       £10.00  ; milk\n" +
+				"    ; bread, receipt:\n" +
+				"  assets:current\n",
+		},
+		{
+			desc: "ledger",
+			s:    &LedgerSerializer{},
+			want: "account assets:current\n" +
+				"account expenses:food\n" +
+				"\n2017/01/12 * (123) Dave   ; weekly shop\n" +
+				"    ; shop: tesco\n" +
+				"  ; Groceries\n" +
+				"  ! expenses:food  £10.00  ; milk\n" +
+				"    ; bread\n" +
+				"    ; :receipt:\n" +
+				"  assets:current\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := test.s.Serialize(&got, testJournal); err != nil {
+				t.Fatalf("Serialize() error: %v", err)
+			}
+			if got.String() != test.want {
+				t.Errorf("Serialize()=%q want %q", got.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestSerialize_amountColumn(t *testing.T) {
+	gbp := NewCommodity("£")
+	j := &Journal{Transactions: []*Transaction{{
+		Date: d1,
+		Postings: []Posting{
+			{Account: Account{"expenses", "Food"}, Amount: Amount{MustParseDecimal("5.00"), gbp}},
+			{Account: Account{"expenses", "Household", "Cleaning Products"}, Amount: Amount{MustParseDecimal("12.50"), gbp}},
+			{Account: Account{"assets", "Current"}, Amount: Amount{MustParseDecimal("-17.50"), gbp}},
+		},
+	}}}
+	lines := func(foodPad, householdPad int) string {
+		return "\n2017/01/12\n" +
+			"  expenses:food" + strings.Repeat(" ", foodPad) + "£5.00\n" +
+			"  expenses:household:cleaning_products" + strings.Repeat(" ", householdPad) + "£12.50\n" +
+			"  assets:current\n"
+	}
+	tests := []struct {
+		column int
+		want   string
+	}{
+		{column: 0, want: lines(2, 2)},
+		{column: 52, want: lines(32, 8)},
+		// The widest account and amount need the amounts to end at column 46.
+		{column: 30, want: lines(26, 2)},
+	}
+	for _, test := range tests {
+		var got bytes.Buffer
+		if err := (&HledgerSerializer{AmountColumn: test.column}).Serialize(&got, j); err != nil {
+			t.Fatalf("Serialize() error: %v", err)
+		}
+		if got.String() != test.want {
+			t.Errorf("Serialize() with AmountColumn %d=%q want %q", test.column, got.String(), test.want)
+		}
+	}
+}
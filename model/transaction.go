@@ -31,12 +31,87 @@ func (s Status) String() string {
 // Account is an account name, modelled as a label hierarchy.
 type Account []string
 
+// AccountType classifies an Account for reports such as hledger's balancesheet
+// and incomestatement.
+type AccountType int
+
+// Account types from http://hledger.org/journal.html#account-types
+const (
+	UntypedAccount AccountType = 0 // UntypedAccount is used for Accounts whose type is not known.
+	Asset          AccountType = 1 // Asset is used for things owned.
+	Liability      AccountType = 2 // Liability is used for things owed.
+	Equity         AccountType = 3 // Equity is used for the balancing accounts of opening balances and the like.
+	Revenue        AccountType = 4 // Revenue is used for income.
+	Expense        AccountType = 5 // Expense is used for spending.
+	Cash           AccountType = 6 // Cash is used for Assets that are cash or can be spent like it, eg. current accounts.
+)
+
+// String conforms with Stringer for AccountType values.
+func (t AccountType) String() string {
+	switch t {
+	case Asset:
+		return "Asset"
+	case Liability:
+		return "Liability"
+	case Equity:
+		return "Equity"
+	case Revenue:
+		return "Revenue"
+	case Expense:
+		return "Expense"
+	case Cash:
+		return "Cash"
+	}
+	return ""
+}
+
+// AccountDeclaration declares an Account, and optionally its type.
+type AccountDeclaration struct {
+	Account Account
+	Type    AccountType
+}
+
+// Tag is a name and optional value attached to a Transaction or Posting, which
+// hledger writes in its comment as name:value.
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// String conforms with Stringer for Tag values.
+func (t Tag) String() string {
+	return t.Name + ":" + t.Value
+}
+
 // Posting models a credit to, or debit from, a particular Account.
 type Posting struct {
-	Status  Status
-	Account Account
-	Amount  float64
-	Comment string    // Additional comments about the Posting.
+	Status    Status
+	Account   Account
+	Amount    Amount
+	Cost      *Amount // Optional cost of Amount, eg. the price paid for shares.
+	TotalCost bool    // Whether Cost is the total cost (@@) rather than the unit cost (@).
+	Assertion *Amount // Optional balance that the Account must have after the Posting.
+	Comment   string  // Additional comments about the Posting.
+	Tags      []Tag   // Tags on the Posting.
+}
+
+// Weight returns the Amount that the Posting contributes towards balancing its
+// Transaction.  This is the Posting's Amount, or the total cost of that Amount
+// if it has a Cost.  A total worked out from a unit Cost is rounded, halves
+// away from zero, to the decimal places of the Cost.
+func (p *Posting) Weight() Amount {
+	switch {
+	case p.Cost == nil:
+		return p.Amount
+	case p.TotalCost:
+		q := p.Cost.Quantity.Abs()
+		if p.Amount.Quantity.Sign() < 0 {
+			q = q.Neg()
+		}
+		return Amount{Quantity: q, Commodity: p.Cost.Commodity}
+	}
+	q := p.Amount.Quantity.Mul(p.Cost.Quantity).Round(p.Cost.Quantity.Scale())
+	return Amount{Quantity: q, Commodity: p.Cost.Commodity}
 }
 
 // Transaction represents the movement of funds between two or more Accounts.
@@ -48,5 +123,13 @@ type Transaction struct {
 	Payee         string    // The Transaction payee.
 	Description   string    // A descriptive label for the Transaction.
 	Comment       string    // Additional comments about the Transaction.
+	Tags          []Tag     // Tags on the Transaction.
 	Postings      []Posting // Two or more Accounts that were involved in the Transaction.
 }
+
+// Journal is a set of AccountDeclarations and Transactions, such as those read
+// from an hledger journal.
+type Journal struct {
+	Accounts     []*AccountDeclaration
+	Transactions []*Transaction
+}
@@ -5,7 +5,7 @@ import (
 )
 
 func TestStatusString(t *testing.T) {
-	tests := []struct{
+	tests := []struct {
 		stat Status
 		want string
 	}{
@@ -19,4 +19,67 @@ func TestStatusString(t *testing.T) {
 			t.Errorf("%v.String()=%q want %q", test.stat, got, want)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestPostingWeight(t *testing.T) {
+	gbp := NewCommodity("£")
+	vwrl := NewCommodity("VWRL")
+	tests := []struct {
+		desc string
+		p    *Posting
+		want Amount
+	}{
+		{
+			desc: "no cost",
+			p:    &Posting{Amount: Amount{MustParseDecimal("12.34"), gbp}},
+			want: Amount{MustParseDecimal("12.34"), gbp},
+		},
+		{
+			desc: "unit cost",
+			p:    &Posting{Amount: Amount{MustParseDecimal("10"), vwrl}, Cost: &Amount{MustParseDecimal("50.25"), gbp}},
+			want: Amount{MustParseDecimal("502.50"), gbp},
+		},
+		{
+			desc: "unit cost of fractional shares",
+			p:    &Posting{Amount: Amount{MustParseDecimal("-10.0"), vwrl}, Cost: &Amount{MustParseDecimal("2.50"), gbp}},
+			want: Amount{MustParseDecimal("-25.00"), gbp},
+		},
+		{
+			desc: "unit cost rounded to its places",
+			p:    &Posting{Amount: Amount{MustParseDecimal("1.5"), vwrl}, Cost: &Amount{MustParseDecimal("0.33"), gbp}},
+			want: Amount{MustParseDecimal("0.50"), gbp},
+		},
+		{
+			desc: "total cost of shares sold",
+			p:    &Posting{Amount: Amount{MustParseDecimal("-10"), vwrl}, Cost: &Amount{MustParseDecimal("502.50"), gbp}, TotalCost: true},
+			want: Amount{MustParseDecimal("-502.50"), gbp},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := test.p.Weight(); got != test.want {
+				t.Errorf("Weight()=%v want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAccountTypeString(t *testing.T) {
+	tests := []struct {
+		typ  AccountType
+		want string
+	}{
+		{UntypedAccount, ""},
+		{Asset, "Asset"},
+		{Liability, "Liability"},
+		{Equity, "Equity"},
+		{Revenue, "Revenue"},
+		{Expense, "Expense"},
+		{Cash, "Cash"},
+	}
+	for _, test := range tests {
+		if got, want := test.typ.String(), test.want; got != want {
+			t.Errorf("%d.String()=%q want %q", test.typ, got, want)
+		}
+	}
+}
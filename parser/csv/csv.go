@@ -0,0 +1,255 @@
+// Package csv contains functions to parse bank statements exported in CSV
+// format, whose layout is described by a Profile for each bank.
+package csv
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/phad/msmtohl/parser/qif"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// Column identifies a column of a CSV file, by the name given in its header
+// row or else by its index, counting from 0.  In JSON a Column is written as
+// either a string or a number.
+type Column struct {
+	Header string
+	Index  int
+}
+
+// UnmarshalJSON sets c from a JSON string or number.
+func (c *Column) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &c.Index); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, &c.Header); err != nil {
+		return fmt.Errorf("column must be a header name or an index: %s", b)
+	}
+	return nil
+}
+
+// Profile describes the layout of the CSV statements exported by a bank.
+// Amounts are given either by a single signed Amount column, or by separate
+// Debit and Credit columns.
+type Profile struct {
+	Name        string  `json:"name"`
+	Skip        int     `json:"skip"`         // Rows to skip before the header row, or the first record.
+	Header      bool    `json:"header"`       // Whether the columns are named by a header row.
+	Comma       string  `json:"comma"`        // The field delimiter.  The default is a comma.
+	DateFormat  string  `json:"date_format"`  // Layout of dates, as for time.Parse, eg. "02/01/2006".
+	AccountType string  `json:"account_type"` // QIF account type, eg. CCard.  The default is Bank.
+	Date        *Column `json:"date"`
+	Amount      *Column `json:"amount"`
+	Debit       *Column `json:"debit"`  // Money out, whatever its sign.
+	Credit      *Column `json:"credit"` // Money in.
+	Payee       *Column `json:"payee"`
+	Memo        *Column `json:"memo"`
+	Category    *Column `json:"category"`
+	ID          *Column `json:"id"` // The bank's identifier for the transaction.
+}
+
+// LoadProfile reads a Profile from its JSON representation, and checks that it
+// describes every column needed.
+func LoadProfile(r io.Reader) (*Profile, error) {
+	p := &Profile{}
+	if err := json.NewDecoder(r).Decode(p); err != nil {
+		return nil, fmt.Errorf("CSV profile: %v", err)
+	}
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// check returns an error unless p describes the columns needed to read a
+// statement.
+func (p *Profile) check() error {
+	switch {
+	case p.Date == nil || p.DateFormat == "":
+		return fmt.Errorf("CSV profile %q: a date column and date_format are required", p.Name)
+	case p.Payee == nil:
+		return fmt.Errorf("CSV profile %q: a payee column is required", p.Name)
+	case (p.Amount == nil) == (p.Debit == nil || p.Credit == nil):
+		return fmt.Errorf("CSV profile %q: either an amount column, or both debit and credit columns, are required", p.Name)
+	}
+	for _, c := range p.columns() {
+		if c.Header != "" && !p.Header {
+			return fmt.Errorf("CSV profile %q: column %q is named, but there is no header row", p.Name, c.Header)
+		}
+	}
+	return nil
+}
+
+// columns returns every Column described by p.
+func (p *Profile) columns() []*Column {
+	var cols []*Column
+	for _, c := range []*Column{p.Date, p.Amount, p.Debit, p.Credit, p.Payee, p.Memo, p.Category, p.ID} {
+		if c != nil {
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// matches reports whether header, a header row, names every column that p
+// names.
+func (p *Profile) matches(header []string) bool {
+	if !p.Header {
+		return false
+	}
+	for _, c := range p.columns() {
+		if c.Header != "" && indexOf(header, c.Header) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Detect returns the built-in Profile whose columns are named by header, the
+// header row of a CSV file, or nil if there isn't one.
+func Detect(header []string) *Profile {
+	for _, name := range profileNames {
+		if p := Profiles[name]; p.matches(header) {
+			return p
+		}
+	}
+	return nil
+}
+
+// ReadFile reads the rows of a CSV statement laid out as described by p as the
+// records of the named account.  If p is nil, the Profile is detected from the
+// header row.  Character set conversion to UTF-8 is performed by dec, or not at
+// all if dec is nil.
+func ReadFile(r io.Reader, p *Profile, account string, dec *encoding.Decoder) (*qif.File, error) {
+	if dec != nil {
+		r = transform.NewReader(r, dec)
+	}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	if p != nil && p.Comma != "" {
+		cr.Comma = []rune(p.Comma)[0]
+	}
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV: %v", err)
+	}
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+	}
+	if p == nil {
+		if len(rows) == 0 || Detect(rows[0]) == nil {
+			return nil, fmt.Errorf("CSV: header row doesn't match any known profile")
+		}
+		p = Detect(rows[0])
+	}
+	if p.Skip > len(rows) {
+		return nil, fmt.Errorf("CSV: can't skip %d rows of %d", p.Skip, len(rows))
+	}
+	idx, rows, err := p.indexes(rows[p.Skip:])
+	if err != nil {
+		return nil, err
+	}
+
+	typ := p.AccountType
+	if typ == "" {
+		typ = "Bank"
+	}
+	rs := &qif.RecordSet{
+		Account: &qif.Account{Name: account, Type: typ},
+		Opening: &qif.Record{Type: "Type:" + typ, Label: account, Transfer: true},
+	}
+	for i, row := range rows {
+		rec, err := p.record(row, idx)
+		if err != nil {
+			return nil, fmt.Errorf("CSV: row %d: %v", i+1, err)
+		}
+		if rec != nil {
+			rs.Records = append(rs.Records, rec)
+		}
+	}
+	return &qif.File{Accounts: []*qif.RecordSet{rs}}, nil
+}
+
+// indexes returns the index of each of p's columns, and the rows of records
+// that follow any header row naming them.
+func (p *Profile) indexes(rows [][]string) (map[*Column]int, [][]string, error) {
+	idx := map[*Column]int{}
+	for _, c := range p.columns() {
+		idx[c] = c.Index
+	}
+	if !p.Header {
+		return idx, rows, nil
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("CSV: no header row")
+	}
+	for _, c := range p.columns() {
+		if c.Header == "" {
+			continue
+		}
+		if idx[c] = indexOf(rows[0], c.Header); idx[c] < 0 {
+			return nil, nil, fmt.Errorf("CSV: no column %q in header %q", c.Header, rows[0])
+		}
+	}
+	return idx, rows[1:], nil
+}
+
+// record returns a qif.Record for a row, given the index of each column, or
+// nil if the row is blank.
+func (p *Profile) record(row []string, idx map[*Column]int) (*qif.Record, error) {
+	if len(row) == 0 || len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+		return nil, nil
+	}
+	get := func(c *Column) string {
+		if c == nil || idx[c] >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[idx[c]])
+	}
+	d, err := time.Parse(p.DateFormat, get(p.Date))
+	if err != nil {
+		return nil, err
+	}
+	amount := get(p.Amount)
+	if p.Amount == nil {
+		debit, credit := get(p.Debit), get(p.Credit)
+		switch {
+		case debit != "" && credit != "":
+			return nil, fmt.Errorf("both debit %q and credit %q", debit, credit)
+		case debit != "":
+			amount = "-" + strings.TrimLeft(debit, "+-")
+		default:
+			amount = credit
+		}
+	}
+	if amount == "" {
+		return nil, fmt.Errorf("no amount")
+	}
+	return &qif.Record{
+		Date:   qif.FormatDate(d),
+		Amount: amount,
+		ID:     get(p.ID),
+		// Transactions on a bank statement have cleared.
+		Cleared: "X",
+		Payee:   get(p.Payee),
+		Memo:    get(p.Memo),
+		Label:   get(p.Category),
+	}, nil
+}
+
+// indexOf returns the index of the column named name in header, or -1.
+func indexOf(header []string, name string) int {
+	for i, h := range header {
+		if strings.TrimSpace(h) == name {
+			return i
+		}
+	}
+	return -1
+}
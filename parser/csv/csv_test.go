@@ -0,0 +1,172 @@
+package csv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/phad/msmtohl/parser/qif"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestLoadProfile(t *testing.T) {
+	tests := []struct {
+		desc    string
+		json    string
+		want    *Profile
+		wantErr bool
+	}{
+		{
+			desc: "debit and credit columns by index",
+			json: `{"name": "mybank", "skip": 3, "comma": ";", "date_format": "2006-01-02",
+				"date": 0, "payee": 1, "debit": 2, "credit": 3}`,
+			want: &Profile{
+				Name:       "mybank",
+				Skip:       3,
+				Comma:      ";",
+				DateFormat: "2006-01-02",
+				Date:       &Column{},
+				Payee:      &Column{Index: 1},
+				Debit:      &Column{Index: 2},
+				Credit:     &Column{Index: 3},
+			},
+		},
+		{
+			desc: "columns by header",
+			json: `{"header": true, "date_format": "02/01/2006", "date": "Date", "payee": "Name", "amount": "Amount", "account_type": "CCard"}`,
+			want: &Profile{
+				Header:      true,
+				DateFormat:  "02/01/2006",
+				AccountType: "CCard",
+				Date:        &Column{Header: "Date"},
+				Payee:       &Column{Header: "Name"},
+				Amount:      &Column{Header: "Amount"},
+			},
+		},
+		{
+			desc:    "no amount",
+			json:    `{"date_format": "02/01/2006", "date": 0, "payee": 1, "debit": 2}`,
+			wantErr: true,
+		},
+		{
+			desc:    "amount and debit",
+			json:    `{"date_format": "02/01/2006", "date": 0, "payee": 1, "amount": 2, "debit": 3, "credit": 4}`,
+			wantErr: true,
+		},
+		{
+			desc:    "no date format",
+			json:    `{"date": 0, "payee": 1, "amount": 2}`,
+			wantErr: true,
+		},
+		{
+			desc:    "named column without header",
+			json:    `{"date_format": "02/01/2006", "date": "Date", "payee": 1, "amount": 2}`,
+			wantErr: true,
+		},
+		{
+			desc:    "bad column",
+			json:    `{"date_format": "02/01/2006", "date": true, "payee": 1, "amount": 2}`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := LoadProfile(strings.NewReader(test.json))
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("LoadProfile()=_, err? %t want? %t (err=%v)", gotErr, test.wantErr, err)
+			}
+			if err == nil && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("LoadProfile()=%+v want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	tests := []struct {
+		desc    string
+		csv     string
+		profile *Profile
+		dec     *encoding.Decoder
+		want    []*qif.Record
+		wantErr bool
+	}{
+		{
+			desc: "monzo, detected",
+			csv: "\ufeffTransaction ID,Date,Time,Type,Name,Emoji,Category,Amount,Currency,Notes and #tags\n" +
+				"tx_0001,12/01/2017,09:00:00,Card payment,Pret A Manger,,Eating out,-4.50,GBP,Breakfast\n" +
+				"tx_0002,13/01/2017,10:00:00,Faster payment,\"Dave, Jones\",,Income,100.00,GBP,\n",
+			want: []*qif.Record{
+				{Date: "12/01'2017", Amount: "-4.50", ID: "tx_0001", Cleared: "X", Payee: "Pret A Manger", Memo: "Breakfast", Label: "Eating out"},
+				{Date: "13/01'2017", Amount: "100.00", ID: "tx_0002", Cleared: "X", Payee: "Dave, Jones", Label: "Income"},
+			},
+		},
+		{
+			desc: "starling, detected",
+			csv: "Date,Counter Party,Reference,Type,Amount (GBP),Balance (GBP),Spending Category,Notes\n" +
+				"31/12/1999,Tesco,TESCO 123,CARD,-12.34,87.66,GROCERIES,\n",
+			want: []*qif.Record{
+				{Date: "31/12/1999", Amount: "-12.34", Cleared: "X", Payee: "Tesco", Memo: "TESCO 123", Label: "GROCERIES"},
+			},
+		},
+		{
+			desc: "barclays, detected",
+			csv: "Number,Date,Account,Amount,Subcategory,Memo\n" +
+				" ,12/01/2017,20-00-00 12345678,-20.00,Debit,SHELL GARAGE    ON 11 JAN          BCC\n" +
+				"\n",
+			want: []*qif.Record{
+				{Date: "12/01'2017", Amount: "-20.00", Cleared: "X", Payee: "SHELL GARAGE    ON 11 JAN          BCC"},
+			},
+		},
+		{
+			desc: "debit and credit columns by index",
+			csv: "Statement for 12345678\n\n" +
+				"2017-01-12;Caf\xe9;10.50;\n" +
+				"2017-01-13;Salary;;1000.00\n",
+			dec:     charmap.ISO8859_15.NewDecoder(),
+			profile: &Profile{Skip: 1, Comma: ";", DateFormat: "2006-01-02", Date: &Column{}, Payee: &Column{Index: 1}, Debit: &Column{Index: 2}, Credit: &Column{Index: 3}},
+			want: []*qif.Record{
+				{Date: "12/01'2017", Amount: "-10.50", Cleared: "X", Payee: "Café"},
+				{Date: "13/01'2017", Amount: "1000.00", Cleared: "X", Payee: "Salary"},
+			},
+		},
+		{
+			desc:    "unknown header",
+			csv:     "When,Who,How much\n",
+			wantErr: true,
+		},
+		{
+			desc:    "bad date",
+			csv:     "Number,Date,Account,Amount,Subcategory,Memo\n1,2017-01-12,1,-20.00,Debit,SHELL\n",
+			wantErr: true,
+		},
+		{
+			desc:    "missing column",
+			csv:     "Date,Payee\n12/01/2017,Shop\n",
+			profile: Profiles["barclays"],
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := ReadFile(strings.NewReader(test.csv), test.profile, "Current", test.dec)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("ReadFile()=_, err? %t want? %t (err=%v)", gotErr, test.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			rs := got.Accounts[0]
+			if want := (&qif.Account{Name: "Current", Type: "Bank"}); !reflect.DeepEqual(rs.Account, want) {
+				t.Errorf("ReadFile() account=%+v want %+v", rs.Account, want)
+			}
+			if !reflect.DeepEqual(rs.Records, test.want) {
+				for i := range rs.Records {
+					t.Logf("got record %d: %+v", i, rs.Records[i])
+				}
+				t.Errorf("ReadFile() records=%v want %v", rs.Records, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package csv
+
+// Profiles holds the built-in profiles, by name.
+var Profiles = map[string]*Profile{
+	"monzo": {
+		Name:       "monzo",
+		Header:     true,
+		DateFormat: "02/01/2006",
+		Date:       &Column{Header: "Date"},
+		Amount:     &Column{Header: "Amount"},
+		Payee:      &Column{Header: "Name"},
+		Memo:       &Column{Header: "Notes and #tags"},
+		Category:   &Column{Header: "Category"},
+		ID:         &Column{Header: "Transaction ID"},
+	},
+	"starling": {
+		Name:       "starling",
+		Header:     true,
+		DateFormat: "02/01/2006",
+		Date:       &Column{Header: "Date"},
+		Amount:     &Column{Header: "Amount (GBP)"},
+		Payee:      &Column{Header: "Counter Party"},
+		Memo:       &Column{Header: "Reference"},
+		Category:   &Column{Header: "Spending Category"},
+	},
+	"barclays": {
+		Name:       "barclays",
+		Header:     true,
+		DateFormat: "02/01/2006",
+		Date:       &Column{Header: "Date"},
+		Amount:     &Column{Header: "Amount"},
+		Payee:      &Column{Header: "Memo"},
+	},
+}
+
+// profileNames lists the built-in profiles in the order Detect tries them,
+// those naming more columns first.
+var profileNames = []string{"monzo", "starling", "barclays"}
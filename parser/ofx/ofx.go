@@ -0,0 +1,312 @@
+// Package ofx contains functions to parse bank and credit card statements
+// presented in the OFX format, as downloaded from banks in .ofx or .qfx files.
+package ofx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/phad/msmtohl/model"
+	"github.com/phad/msmtohl/parser/qif"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// element is an OFX aggregate, such as <STMTTRN>, or a leaf element holding a
+// value, such as <TRNAMT>.
+type element struct {
+	name     string
+	value    string
+	children []*element
+	parent   *element
+}
+
+// child returns the first child of e with the given name, or nil if there is
+// none.
+func (e *element) child(name string) *element {
+	if e == nil {
+		return nil
+	}
+	for _, c := range e.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// get returns the value of the leaf element found by following the given path
+// of names from e, or "" if there is none.
+func (e *element) get(path ...string) string {
+	for _, name := range path {
+		e = e.child(name)
+	}
+	if e == nil {
+		return ""
+	}
+	return e.value
+}
+
+// findAll returns every element below e with the given name.
+func (e *element) findAll(name string) []*element {
+	if e == nil {
+		return nil
+	}
+	var found []*element
+	for _, c := range e.children {
+		if c.name == name {
+			found = append(found, c)
+		}
+		found = append(found, c.findAll(name)...)
+	}
+	return found
+}
+
+// IsOFX reports whether data, the start of a file, looks like OFX rather than
+// QIF.
+func IsOFX(data []byte) bool {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	for _, prefix := range []string{"OFXHEADER", "<?xml", "<?OFX", "<OFX>"} {
+		if bytes.HasPrefix(data, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFile reads every bank and credit card statement in an OFX file, of
+// either OFX 1.x (SGML) or 2.x (XML), as an account of a qif.File.
+//
+// Character set conversion to UTF-8 is performed by dec or, if dec is nil, as
+// given by the file's header.  Each statement transaction becomes a
+// qif.Record with its FITID as the ID and any cheque number as the Number, and
+// the statement's ledger balance is kept as the Balance of the account.
+// Accounts are named by their account number.  A statement with a ledger
+// balance has an opening balance record, dated the day before its first
+// transaction, for the ledger balance less the transactions up to its date,
+// so that the ledger balance holds; others have no opening balance record.
+func ReadFile(r io.Reader, dec *encoding.Decoder) (*qif.File, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if dec == nil {
+		dec = headerDecoder(data)
+	}
+	if data, err = dec.Bytes(data); err != nil {
+		return nil, fmt.Errorf("OFX: encoding.Decoder.Bytes(): err %v", err)
+	}
+	root, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	f := &qif.File{}
+	for _, stmt := range root.findAll("STMTRS") {
+		rs, err := statement(stmt, "BANKACCTFROM", "Bank")
+		if err != nil {
+			return nil, err
+		}
+		f.Accounts = append(f.Accounts, rs)
+	}
+	for _, stmt := range root.findAll("CCSTMTRS") {
+		rs, err := statement(stmt, "CCACCTFROM", "CCard")
+		if err != nil {
+			return nil, err
+		}
+		f.Accounts = append(f.Accounts, rs)
+	}
+	return f, nil
+}
+
+// headerDecoder returns the Decoder for the character set named in the header
+// of OFX data: UTF-8 for OFX 2.x and OFX 1.x files with ENCODING:UTF-8,
+// otherwise Windows-1252 or ISO-8859-1 as given by CHARSET.
+func headerDecoder(data []byte) *encoding.Decoder {
+	header := string(data)
+	if i := strings.Index(header, "<OFX>"); i >= 0 {
+		header = header[:i]
+	}
+	switch {
+	case strings.Contains(header, "<?xml"), strings.Contains(header, "ENCODING:UTF-8"):
+		return encoding.Nop.NewDecoder()
+	case strings.Contains(header, "CHARSET:1252"):
+		return charmap.Windows1252.NewDecoder()
+	}
+	return charmap.ISO8859_1.NewDecoder()
+}
+
+// parse returns the <OFX> element of OFX data.  Leaf elements of OFX 1.x
+// have no end tags; they end where their value does.
+func parse(data []byte) (*element, error) {
+	i := bytes.Index(data, []byte("<OFX>"))
+	if i < 0 {
+		return nil, fmt.Errorf("OFX: no <OFX> element")
+	}
+	doc := &element{}
+	cur := doc
+	var leaf *element // The last leaf element, whose end tag is optional.
+	s := bufio.NewScanner(bytes.NewReader(data[i:]))
+	s.Split(scanTokens)
+	for s.Scan() {
+		tok := s.Text()
+		switch {
+		case strings.HasPrefix(tok, "</"):
+			name := strings.TrimSuffix(tok[2:], ">")
+			if leaf != nil && leaf.name == name && cur != leaf {
+				leaf = nil
+				continue
+			}
+			for cur != doc && cur.name != name {
+				cur = cur.parent
+			}
+			if cur == doc {
+				return nil, fmt.Errorf("OFX: unexpected %s", tok)
+			}
+			cur = cur.parent
+		case strings.HasPrefix(tok, "<"):
+			e := &element{name: strings.TrimSuffix(tok[1:], ">"), parent: cur}
+			cur.children = append(cur.children, e)
+			cur, leaf = e, nil
+		default:
+			if cur == doc {
+				return nil, fmt.Errorf("OFX: value %q outside any element", tok)
+			}
+			cur.value = unescape(tok)
+			cur, leaf = cur.parent, cur
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("OFX: scanner error: %v", err)
+	}
+	if ofx := doc.child("OFX"); ofx != nil {
+		return ofx, nil
+	}
+	return nil, fmt.Errorf("OFX: no <OFX> element")
+}
+
+// scanTokens is a bufio.SplitFunc returning the tags, and the trimmed values
+// between them, of OFX data.
+func scanTokens(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	for start < len(data) && isSpace(data[start]) {
+		start++
+	}
+	if start == len(data) {
+		return start, nil, nil
+	}
+	if data[start] == '<' {
+		if end := bytes.IndexByte(data[start:], '>'); end >= 0 {
+			return start + end + 1, data[start : start+end+1], nil
+		}
+	} else if end := bytes.IndexByte(data[start:], '<'); end >= 0 {
+		return start + end, bytes.TrimSpace(data[start : start+end]), nil
+	}
+	if atEOF {
+		return len(data), bytes.TrimSpace(data[start:]), nil
+	}
+	return start, nil, nil
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// unescape replaces the character entities OFX uses for reserved characters.
+func unescape(s string) string {
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&", "&nbsp;", " ").Replace(s)
+}
+
+// statement returns a RecordSet for the transactions of a statement, whose
+// account is described by its acct element.
+func statement(stmt *element, acct, typ string) (*qif.RecordSet, error) {
+	name := stmt.get(acct, "ACCTID")
+	if name == "" {
+		return nil, fmt.Errorf("OFX: statement has no %s account number", acct)
+	}
+	a := &qif.Account{Name: name, Type: typ}
+	rs := &qif.RecordSet{
+		Account: a,
+		Opening: &qif.Record{Type: "Type:" + typ, Label: name, Transfer: true},
+	}
+	var dates []time.Time
+	for _, t := range stmt.child("BANKTRANLIST").findAll("STMTTRN") {
+		d, err := parseDate(t.get("DTPOSTED"))
+		if err != nil {
+			return nil, fmt.Errorf("OFX: account %s transaction %s: %v", name, t.get("FITID"), err)
+		}
+		dates = append(dates, d)
+		payee := t.get("NAME")
+		if payee == "" {
+			payee = t.get("PAYEE", "NAME")
+		}
+		rs.Records = append(rs.Records, &qif.Record{
+			Date:   qif.FormatDate(d),
+			Amount: amount(t.get("TRNAMT")),
+			Number: t.get("CHECKNUM"),
+			ID:     t.get("FITID"),
+			// Transactions on a bank statement have cleared.
+			Cleared: "X",
+			Payee:   payee,
+			Memo:    t.get("MEMO"),
+		})
+	}
+	if bal := stmt.get("LEDGERBAL", "BALAMT"); bal != "" {
+		d, err := parseDate(stmt.get("LEDGERBAL", "DTASOF"))
+		if err != nil {
+			return nil, fmt.Errorf("OFX: account %s ledger balance: %v", name, err)
+		}
+		a.Balance, a.BalanceDate = amount(bal), qif.FormatDate(d)
+		if err := opening(rs, dates, d); err != nil {
+			return nil, fmt.Errorf("OFX: account %s: %v", name, err)
+		}
+	}
+	return rs, nil
+}
+
+// opening sets the opening record of rs, a statement whose transactions are
+// dated as given, to the balance before them: its ledger balance, as of the
+// date asOf, less the transactions up to that date.  It's dated the day before
+// the first transaction, or asOf if there are none.
+func opening(rs *qif.RecordSet, dates []time.Time, asOf time.Time) error {
+	bal, err := model.ParseDecimal(rs.Account.Balance)
+	if err != nil {
+		return fmt.Errorf("ledger balance: %v", err)
+	}
+	first := asOf
+	for i, r := range rs.Records {
+		if dates[i].Before(first.AddDate(0, 0, 1)) {
+			first = dates[i].AddDate(0, 0, -1)
+		}
+		if dates[i].After(asOf) {
+			continue
+		}
+		a, err := model.ParseDecimal(r.Amount)
+		if err != nil {
+			return fmt.Errorf("transaction %s: %v", r.ID, err)
+		}
+		bal = bal.Sub(a)
+	}
+	rs.Opening.Date, rs.Opening.Amount = qif.FormatDate(first), bal.String()
+	rs.Opening.Payee, rs.Opening.Cleared = "Opening Balance", "X"
+	return nil
+}
+
+// parseDate parses the date part of an OFX date and time, such as
+// 20170112 or 20170112120000.000[0:GMT].
+func parseDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return time.Parse("20060102", s[:8])
+}
+
+// amount returns an OFX amount as a QIF one.  OFX amounts have no grouping
+// marks, but may use a comma as their decimal mark.
+func amount(s string) string {
+	return strings.Replace(s, ",", ".", 1)
+}
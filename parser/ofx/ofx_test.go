@@ -0,0 +1,183 @@
+package ofx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/phad/msmtohl/parser/qif"
+)
+
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+CHARSET:1252
+COMPRESSION:NONE
+OLDFILEUID:NONE
+NEWFILEUID:NONE
+
+<OFX>
+<SIGNONMSGSRSV1>
+<SONRS>
+<STATUS>
+<CODE>0
+<SEVERITY>INFO
+</STATUS>
+<DTSERVER>20170131120000
+<LANGUAGE>ENG
+</SONRS>
+</SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<TRNUID>1
+<STMTRS>
+<CURDEF>GBP
+<BANKACCTFROM>
+<BANKID>089999
+<ACCTID>12345678
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20170101
+<DTEND>20170131
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20170112120000.000[0:GMT]
+<TRNAMT>-10.50
+<FITID>201701120001
+<NAME>Dave&amp;Sons
+<MEMO>Caf` + "\xe9" + `
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>19991231
+<TRNAMT>1234,56
+<FITID>199912310001
+<CHECKNUM>000123
+<NAME>Salary
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL>
+<BALAMT>1324.06
+<DTASOF>20170131
+</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const xmlStatement = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <TRNUID>1</TRNUID>
+      <CCSTMTRS>
+        <CURDEF>GBP</CURDEF>
+        <CCACCTFROM><ACCTID>4111111111111111</ACCTID></CCACCTFROM>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20170115</DTPOSTED>
+            <TRNAMT>-20.00</TRNAMT>
+            <FITID>A1</FITID>
+            <PAYEE><NAME>Garage</NAME></PAYEE>
+            <MEMO></MEMO>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestReadFile(t *testing.T) {
+	tests := []struct {
+		desc    string
+		ofx     string
+		want    *qif.File
+		wantErr bool
+	}{
+		{
+			desc: "OFX 1.x bank statement",
+			ofx:  sgmlStatement,
+			want: &qif.File{Accounts: []*qif.RecordSet{{
+				Account: &qif.Account{Name: "12345678", Type: "Bank", Balance: "1324.06", BalanceDate: "31/01'2017"},
+				Opening: &qif.Record{Type: "Type:Bank", Date: "30/12/1999", Amount: "100.00", Cleared: "X", Payee: "Opening Balance", Label: "12345678", Transfer: true},
+				Records: []*qif.Record{
+					{Date: "12/01'2017", Amount: "-10.50", ID: "201701120001", Cleared: "X", Payee: "Dave&Sons", Memo: "Café"},
+					{Date: "31/12/1999", Amount: "1234.56", Number: "000123", ID: "199912310001", Cleared: "X", Payee: "Salary"},
+				},
+			}}},
+		},
+		{
+			desc: "OFX 2.x credit card statement",
+			ofx:  xmlStatement,
+			want: &qif.File{Accounts: []*qif.RecordSet{{
+				Account: &qif.Account{Name: "4111111111111111", Type: "CCard"},
+				Opening: &qif.Record{Type: "Type:CCard", Label: "4111111111111111", Transfer: true},
+				Records: []*qif.Record{
+					{Date: "15/01'2017", Amount: "-20.00", ID: "A1", Cleared: "X", Payee: "Garage"},
+				},
+			}}},
+		},
+		{
+			desc:    "not OFX",
+			ofx:     "!Type:Bank\n",
+			wantErr: true,
+		},
+		{
+			desc:    "mismatched end tag",
+			ofx:     "<OFX><STMTRS></BANKTRANLIST></OFX>",
+			wantErr: true,
+		},
+		{
+			desc:    "bad date",
+			ofx:     "<OFX><STMTRS><BANKACCTFROM><ACCTID>1</BANKACCTFROM><BANKTRANLIST><STMTTRN><DTPOSTED>2017</STMTTRN></BANKTRANLIST></STMTRS></OFX>",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := ReadFile(strings.NewReader(test.ofx), nil)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("ReadFile()=_, err? %t want? %t (err=%v)", gotErr, test.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if len(got.Accounts) != len(test.want.Accounts) {
+				t.Fatalf("ReadFile() got %d accounts want %d", len(got.Accounts), len(test.want.Accounts))
+			}
+			for i, rs := range got.Accounts {
+				if want := test.want.Accounts[i]; !reflect.DeepEqual(rs, want) {
+					t.Errorf("ReadFile() account %d=%+v want %+v", i, rs, want)
+					for j := range rs.Records {
+						t.Logf("got record %d: %+v", j, rs.Records[j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIsOFX(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{sgmlStatement, true},
+		{xmlStatement, true},
+		{"\xef\xbb\xbf<OFX>", true},
+		{"!Type:Bank\n", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if got := IsOFX([]byte(test.data)); got != test.want {
+			t.Errorf("IsOFX(%.20q)=%t want %t", test.data, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package qif
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// AmountFormat gives the marks used in the amounts of a QIF file, which vary
+// with the locale it was exported in: 1,234.56 in the UK and US, but 1.234,56
+// or 1 234,56 in much of Europe.
+type AmountFormat struct {
+	DecimalMark  rune // The default is '.'.
+	GroupingMark rune // The default is ',', or '.' if that's not the DecimalMark.
+}
+
+// marks returns the decimal and grouping marks of af.
+func (af AmountFormat) marks() (rune, rune) {
+	dec, grp := af.DecimalMark, af.GroupingMark
+	if dec == 0 {
+		dec = '.'
+	}
+	if grp == 0 {
+		grp = ','
+		if dec == ',' {
+			grp = '.'
+		}
+	}
+	return dec, grp
+}
+
+// AmountError reports an amount that can't be read, and where in it the
+// problem is.
+type AmountError struct {
+	Amount string
+	Column int // The position of the problem in Amount, counting characters from 1.
+	Err    string
+}
+
+// Error conforms with error for AmountErrors.
+func (e *AmountError) Error() string {
+	return fmt.Sprintf("QIF amount %q: column %d: %s", e.Amount, e.Column, e.Err)
+}
+
+// Unwrap returns ErrMalformedAmount, the cause of every AmountError.
+func (e *AmountError) Unwrap() error {
+	return ErrMalformedAmount
+}
+
+// amountScan holds the state of Normalize as it reads an amount.
+type amountScan struct {
+	digits  []byte
+	dec     int   // The index in digits of the first decimal place, or -1.
+	groups  []int // The index in digits of each grouping mark.
+	columns []int // The column of each grouping mark.
+	sign    rune  // Any + or - sign.
+	paren   int   // 1 once ( is read, 2 once ) is read.
+	after   bool  // Whether the last digit has been read.
+}
+
+// Normalize returns the amount s, written in the format af, as an optionally
+// signed decimal number with any decimal places after a '.', as in "-1234.56".
+// A leading or trailing sign, or parentheses, may mark a negative amount, and
+// currency signs and codes around the number are ignored.  An amount whose
+// grouping marks don't separate groups of three digits is reported as
+// ambiguous, since the mark may be a decimal mark instead.  An empty amount is
+// returned as it is.
+func (af AmountFormat) Normalize(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return s, nil
+	}
+	dec, grp := af.marks()
+	rs := []rune(s)
+	sc := &amountScan{dec: -1}
+	fail := func(i int, format string, args ...interface{}) error {
+		return &AmountError{Amount: s, Column: i + 1, Err: fmt.Sprintf(format, args...)}
+	}
+	for i := range rs {
+		if msg := sc.scan(rs, i, dec, grp); msg != "" {
+			return "", fail(i, "%s", msg)
+		}
+	}
+	switch {
+	case len(sc.digits) == 0:
+		return "", fail(0, "no digits")
+	case sc.paren == 1:
+		return "", fail(len(rs)-1, "no closing parenthesis")
+	}
+	if i, err := sc.checkGroups(); err != nil {
+		return "", fail(sc.columns[i], "%q %s, so may be a decimal mark", grp, err)
+	}
+	return sc.String(), nil
+}
+
+// scan reads the rune rs[i] of an amount with the given decimal and grouping
+// marks, returning why it's unexpected if it is.
+func (sc *amountScan) scan(rs []rune, i int, dec, grp rune) string {
+	r := rs[i]
+	between := i > 0 && isDigit(rs[i-1]) && i+1 < len(rs) && isDigit(rs[i+1])
+	switch {
+	case isDigit(r):
+		if sc.after {
+			return "unexpected digit"
+		}
+		sc.digits = append(sc.digits, byte(r))
+		return ""
+	case r == dec:
+		if sc.dec >= 0 || sc.after {
+			return fmt.Sprintf("unexpected decimal mark %q", r)
+		}
+		sc.dec = len(sc.digits)
+		return ""
+	case r == grp && between:
+		if sc.dec >= 0 {
+			return fmt.Sprintf("grouping mark %q after the decimal mark", r)
+		}
+		sc.groups, sc.columns = append(sc.groups, len(sc.digits)), append(sc.columns, i)
+		return ""
+	}
+	msg := sc.scanOther(r)
+	sc.after = len(sc.digits) > 0
+	return msg
+}
+
+// scanOther reads a rune of an amount other than a digit or mark: a sign,
+// parenthesis, space or currency sign or code.
+func (sc *amountScan) scanOther(r rune) string {
+	switch {
+	case r == '-' || r == '+':
+		if sc.sign != 0 || sc.paren != 0 {
+			return fmt.Sprintf("unexpected sign %q", r)
+		}
+		sc.sign = r
+	case r == '(':
+		if len(sc.digits) > 0 || sc.sign != 0 || sc.paren != 0 {
+			return fmt.Sprintf("unexpected %q", r)
+		}
+		sc.paren = 1
+	case r == ')':
+		if sc.paren != 1 || len(sc.digits) == 0 {
+			return fmt.Sprintf("unexpected %q", r)
+		}
+		sc.paren = 2
+	case unicode.IsSpace(r), unicode.Is(unicode.Sc, r), unicode.IsLetter(r):
+		// Currency signs and codes, and spaces, around the number.
+	default:
+		return fmt.Sprintf("unexpected %q", r)
+	}
+	return ""
+}
+
+// checkGroups returns an error, and the index of the grouping mark it's
+// about, unless the grouping marks separate the integer digits into groups of
+// three, after a first group of up to three.
+func (sc *amountScan) checkGroups() (int, error) {
+	if len(sc.groups) == 0 {
+		return 0, nil
+	}
+	if n := sc.groups[0]; n > 3 {
+		return 0, fmt.Errorf("follows %d digits, not up to 3", n)
+	}
+	end := len(sc.digits)
+	if sc.dec >= 0 {
+		end = sc.dec
+	}
+	for i, g := range sc.groups {
+		next := end
+		if i+1 < len(sc.groups) {
+			next = sc.groups[i+1]
+		}
+		if n := next - g; n != 3 {
+			return i, fmt.Errorf("is followed by %d digits, not 3", n)
+		}
+	}
+	return 0, nil
+}
+
+// String returns the amount read, as Normalize returns it.
+func (sc *amountScan) String() string {
+	whole, frac := string(sc.digits), ""
+	if sc.dec >= 0 {
+		whole, frac = string(sc.digits[:sc.dec]), string(sc.digits[sc.dec:])
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if frac != "" {
+		whole += "." + frac
+	}
+	if sc.sign == '-' || sc.paren != 0 {
+		return "-" + whole
+	}
+	return whole
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// NormalizeAmounts rewrites every amount of the records and accounts of f,
+// written in the format af, as Normalize returns it.  This lets the records of
+// files from different locales be converted together.  An amount read from
+// QIF data that can't be normalised is reported by a ParseError giving the
+// line its record or account header starts on.  Split percentages are
+// rewritten the same way, without their % sign.
+func (f *File) NormalizeAmounts(af AmountFormat) error {
+	for _, a := range f.amounts() {
+		s := *a.s
+		if a.field == "%" {
+			s = strings.TrimSuffix(strings.TrimSpace(s), "%")
+		}
+		n, err := af.Normalize(s)
+		if err != nil {
+			return f.valueError(a, err)
+		}
+		*a.s = n
+	}
+	return nil
+}
+
+// amounts returns every amount of f: those of its records and splits, its
+// splits' percentages, and its accounts' statement balances and credit limits.
+func (f *File) amounts() []value {
+	var as []value
+	for _, rs := range f.Accounts {
+		if a := rs.Account; a != nil {
+			as = append(as, value{&a.Balance, "$", a.Line, a.Index}, value{&a.CreditLimit, "L", a.Line, a.Index})
+		}
+		for _, r := range append([]*Record{rs.Opening}, rs.Records...) {
+			if r == nil {
+				continue
+			}
+			as = append(as,
+				value{&r.Amount, "T", r.Line, r.Index},
+				value{&r.Price, "I", r.Line, r.Index},
+				value{&r.Quantity, "Q", r.Line, r.Index},
+				value{&r.Commission, "O", r.Line, r.Index},
+				value{&r.TransferAmount, "$", r.Line, r.Index})
+			for _, s := range r.Splits {
+				as = append(as, value{&s.Amount, "$", r.Line, r.Index}, value{&s.Percent, "%", r.Line, r.Index})
+			}
+		}
+	}
+	return as
+}
@@ -0,0 +1,88 @@
+package qif
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAmountFormat_Normalize(t *testing.T) {
+	eu := AmountFormat{DecimalMark: ','}
+	fr := AmountFormat{DecimalMark: ',', GroupingMark: ' '}
+	tests := []struct {
+		format  AmountFormat
+		in      string
+		want    string
+		wantCol int
+	}{
+		{in: "", want: ""},
+		{in: "12.34", want: "12.34"},
+		{in: "-12.34", want: "-12.34"},
+		{in: "+12", want: "12"},
+		{in: ".5", want: "0.5"},
+		{in: "12.", want: "12"},
+		{in: "1,234,567.89", want: "1234567.89"},
+		{in: "12.34-", want: "-12.34"},
+		{in: "(1,234.56)", want: "-1234.56"},
+		{in: "£-12.34", want: "-12.34"},
+		{in: "-$12.34", want: "-12.34"},
+		{in: " 12.34 GBP ", want: "12.34"},
+		{in: "(£12.34)", want: "-12.34"},
+		{format: eu, in: "1.234,56", want: "1234.56"},
+		{format: eu, in: "-1.234", want: "-1234"},
+		{format: eu, in: "12,5 €", want: "12.5"},
+		{format: fr, in: "1 234 567,89", want: "1234567.89"},
+		{format: fr, in: "-1 234,56 €", want: "-1234.56"},
+		{in: "12,34", wantCol: 3},
+		{in: "1234,567.00", wantCol: 5},
+		{in: "1,2345", wantCol: 2},
+		{in: "1,234,56", wantCol: 6},
+		{in: "1.234,56", wantCol: 6},
+		{in: "1.2.3", wantCol: 4},
+		{in: "12 34", wantCol: 4},
+		{in: "12a34", wantCol: 4},
+		{in: "--12", wantCol: 2},
+		{in: "-(12)", wantCol: 2},
+		{in: "(12", wantCol: 3},
+		{in: "12)", wantCol: 3},
+		{in: "12#", wantCol: 3},
+		{in: "£", wantCol: 1},
+		{format: eu, in: "1.234.56", wantCol: 6},
+		{format: eu, in: "1234.56", wantCol: 5},
+	}
+	for _, test := range tests {
+		got, err := test.format.Normalize(test.in)
+		if test.wantCol > 0 {
+			ae, ok := err.(*AmountError)
+			if !ok || ae.Column != test.wantCol {
+				t.Errorf("%+v Normalize(%q)=%q, %v want *AmountError at column %d", test.format, test.in, got, err, test.wantCol)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("%+v Normalize(%q)=%q, %v want %q", test.format, test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestFile_NormalizeAmounts(t *testing.T) {
+	qif := "!Account\nNCurrent\nTBank\n/31/01'17\n$1.234,56\n^\n" +
+		"!Type:Bank\nD1/1'17\nT-1.000,00\nSFood\n$-999,50\nSHome\n$-0,50\n^\n" +
+		"D2/1'17\nT-10,00\nSFood\n%50,5%\nSHome\n%49,5\n^\n"
+	f, err := ReadFile(strings.NewReader(qif), decoder)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if err := f.NormalizeAmounts(AmountFormat{DecimalMark: ','}); err != nil {
+		t.Fatalf("NormalizeAmounts() error: %v", err)
+	}
+	rs := f.Accounts[0]
+	r, p := rs.Records[0], rs.Records[1]
+	got := []string{rs.Account.Balance, r.Amount, r.Splits[0].Amount, r.Splits[1].Amount, p.Splits[0].Percent, p.Splits[1].Percent}
+	if want := []string{"1234.56", "-1000.00", "-999.50", "-0.50", "50.5", "49.5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeAmounts() amounts=%q want %q", got, want)
+	}
+	if err := f.NormalizeAmounts(AmountFormat{DecimalMark: ','}); err == nil {
+		t.Errorf("NormalizeAmounts() of normalized amounts got no error")
+	}
+}
@@ -0,0 +1,53 @@
+package qif
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// DetectEncoding returns the character encoding that data, the content of a
+// QIF file, appears to be in: UTF-8 if it starts with a UTF-8 byte order mark
+// or is valid UTF-8, otherwise a single-byte code page.  That's Windows-1252
+// if data has any of the bytes 0x80 to 0x9F, which are printable characters
+// such as € only in Windows-1252, and ISO-8859-15, as Money writes, if not.
+// The decoders of the UTF-8 encoding returned strip any byte order mark.
+func DetectEncoding(data []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xef\xbb\xbf")), utf8.Valid(data):
+		return unicode.UTF8BOM
+	}
+	for _, b := range data {
+		if b >= 0x80 && b <= 0x9f {
+			return charmap.Windows1252
+		}
+	}
+	return charmap.ISO8859_15
+}
+
+// LookupEncoding returns the character encoding of the given name: UTF-8, or
+// any of the golang.org/x/text/encoding/charmap encodings.  Names are matched
+// ignoring case, spaces, hyphens and underscores, so that "ISO 8859-15",
+// "iso-8859-15" and ISO8859_15 all name the same encoding.
+func LookupEncoding(name string) (encoding.Encoding, error) {
+	key := encodingKey(name)
+	if key == "utf8" {
+		return unicode.UTF8BOM, nil
+	}
+	for _, e := range charmap.All {
+		if cm, ok := e.(*charmap.Charmap); ok && encodingKey(cm.String()) == key {
+			return cm, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown character encoding %q", name)
+}
+
+// encodingKey returns the encoding name n in the form LookupEncoding matches.
+func encodingKey(n string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(n))
+}
@@ -0,0 +1,88 @@
+package qif
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		desc string
+		data string
+		want encoding.Encoding
+	}{
+		{desc: "empty", data: "", want: unicode.UTF8BOM},
+		{desc: "ASCII", data: "!Type:Bank\nT-1.00\n^\n", want: unicode.UTF8BOM},
+		{desc: "UTF-8", data: "PCaf\xc3\xa9 \xc2\xa3\n", want: unicode.UTF8BOM},
+		{desc: "UTF-8 BOM", data: "\xef\xbb\xbf!Type:Bank\n", want: unicode.UTF8BOM},
+		{desc: "Windows-1252 euro", data: "PCaf\xe9 \x80\n", want: charmap.Windows1252},
+		{desc: "ISO-8859-15", data: "PCaf\xe9 \xa3 \xa4\n", want: charmap.ISO8859_15},
+	}
+	for _, test := range tests {
+		if got := DetectEncoding([]byte(test.data)); got != test.want {
+			t.Errorf("%s: DetectEncoding(%q)=%v want %v", test.desc, test.data, got, test.want)
+		}
+	}
+}
+
+func TestLookupEncoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    encoding.Encoding
+		wantErr bool
+	}{
+		{name: "UTF-8", want: unicode.UTF8BOM},
+		{name: "utf8", want: unicode.UTF8BOM},
+		{name: "ISO 8859-15", want: charmap.ISO8859_15},
+		{name: "iso-8859-15", want: charmap.ISO8859_15},
+		{name: "ISO8859_15", want: charmap.ISO8859_15},
+		{name: "windows-1252", want: charmap.Windows1252},
+		{name: "Macintosh", want: charmap.Macintosh},
+		{name: "latin9", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := LookupEncoding(test.name)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("LookupEncoding(%q)=_, err? %t want? %t (err=%v)", test.name, gotErr, test.wantErr, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("LookupEncoding(%q)=%v want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestReadFile_detectedEncoding(t *testing.T) {
+	tests := []struct {
+		desc string
+		data string
+	}{
+		{desc: "UTF-8 BOM", data: "\xef\xbb\xbf!Type:Bank\nD1/1'17\nT-1.00\nPCaf\xc3\xa9 \xe2\x82\xac\n^\n"},
+		{desc: "Windows-1252", data: "!Type:Bank\nD1/1'17\nT-1.00\nPCaf\xe9 \x80\n^\n"},
+		{desc: "ISO-8859-15", data: "!Type:Bank\nD1/1'17\nT-1.00\nPCaf\xe9 \xa4\n^\n"},
+	}
+	for _, test := range tests {
+		data := []byte(test.data)
+		f, err := ReadFile(bytes.NewReader(data), DetectEncoding(data).NewDecoder())
+		if err != nil {
+			t.Errorf("%s: ReadFile() error: %v", test.desc, err)
+			continue
+		}
+		rs := f.Accounts[0]
+		if got, want := rs.Opening.Type, "Type:Bank"; got != want {
+			t.Errorf("%s: ReadFile() type=%q want %q", test.desc, got, want)
+		}
+		if len(rs.Records) != 1 {
+			t.Errorf("%s: ReadFile() got %d records want 1", test.desc, len(rs.Records))
+			continue
+		}
+		if got, want := rs.Records[0].Payee, "Café €"; got != want {
+			t.Errorf("%s: ReadFile() payee=%q want %q", test.desc, got, want)
+		}
+	}
+}
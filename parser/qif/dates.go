@@ -0,0 +1,166 @@
+package qif
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateOrder is the order in which the day and month of a QIF date are written.
+type DateOrder int
+
+const (
+	// DayMonth dates are written dd/mm/yy, as by Money in the UK.
+	DayMonth DateOrder = iota
+	// MonthDay dates are written mm/dd/yy, as by US Money and Quicken.
+	MonthDay
+)
+
+// DefaultPivot is the century pivot of DateDialects that give none, reading
+// two-digit years as 1970 to 2069.
+const DefaultPivot = 1970
+
+// DateDialect describes how the dates of a QIF file are written.  Dates are
+// written day, month and year, in the Order given, separated by / or by a '
+// before the year, and may be padded with spaces, as in 1/ 2'98.  Years have
+// two or four digits.  ISO dates, yyyy-mm-dd, are read in any dialect.
+type DateDialect struct {
+	Order DateOrder
+	// Pivot is the first of the hundred years that two-digit years fall in,
+	// whichever separator precedes them, or 0 for DefaultPivot.  With a Pivot
+	// of 1930, 29 is read as 2029 and 30 as 1930.
+	Pivot int
+}
+
+// Parse parses a date written in the dialect dd.  Its errors wrap
+// ErrMalformedDate.
+func (dd DateDialect) Parse(d string) (time.Time, error) {
+	s := strings.Replace(strings.TrimSpace(d), " ", "", -1)
+	if t, err := time.Parse("2006-1-2", s); err == nil {
+		return t, nil
+	}
+	f, err := dateFields(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w %q: %v", ErrMalformedDate, d, err)
+	}
+	day, month, year := f[0], f[1], f[2]
+	if dd.Order == MonthDay {
+		day, month = month, day
+	}
+	if year < 100 {
+		year = dd.century(year)
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Day() != day || int(t.Month()) != month {
+		return time.Time{}, fmt.Errorf("%w %q: no such day", ErrMalformedDate, d)
+	}
+	return t, nil
+}
+
+// century returns the two-digit year yy in the hundred years from dd.Pivot.
+func (dd DateDialect) century(yy int) int {
+	pivot := dd.Pivot
+	if pivot == 0 {
+		pivot = DefaultPivot
+	}
+	year := pivot - pivot%100 + yy
+	if year < pivot {
+		year += 100
+	}
+	return year
+}
+
+// dateFields returns the three numbers of a date written with / or ' as
+// separators, in the order they are written.
+func dateFields(s string) ([3]int, error) {
+	var f [3]int
+	i := strings.IndexByte(s, '/')
+	j := strings.LastIndexAny(s, "/'")
+	if i <= 0 || j <= i {
+		return f, fmt.Errorf("not a date")
+	}
+	y := s[j+1:]
+	if len(y) != 2 && len(y) != 4 {
+		return f, fmt.Errorf("year %q must have two or four digits", y)
+	}
+	for k, n := range []string{s[:i], s[i+1 : j], y} {
+		v, err := strconv.Atoi(n)
+		if err != nil || v < 0 {
+			return f, fmt.Errorf("%q is not a number", n)
+		}
+		f[k] = v
+	}
+	return f, nil
+}
+
+// DetectDateOrder returns the order of day and month that every one of dates,
+// written in the same dialect, can be read in.  An order is ruled out by any
+// date with a day over 31 or a month over 12 in that order.  DayMonth is
+// returned if neither order is ruled out, and an error if both are.  Dates
+// that can't be read either way are ignored.
+func DetectDateOrder(dates []string) (DateOrder, error) {
+	dayMonth, monthDay := true, true
+	for _, d := range dates {
+		f, err := dateFields(strings.Replace(strings.TrimSpace(d), " ", "", -1))
+		if err != nil {
+			continue
+		}
+		if f[0] > 12 || f[1] > 31 {
+			monthDay = false
+		}
+		if f[1] > 12 || f[0] > 31 {
+			dayMonth = false
+		}
+	}
+	switch {
+	case dayMonth:
+		return DayMonth, nil
+	case monthDay:
+		return MonthDay, nil
+	}
+	return DayMonth, fmt.Errorf("QIF dates fit neither dd/mm nor mm/dd order")
+}
+
+// dates returns every date of f: those of its records and its accounts'
+// statement balances.
+func (f *File) dates() []value {
+	var ds []value
+	for _, rs := range f.Accounts {
+		if a := rs.Account; a != nil && a.BalanceDate != "" {
+			ds = append(ds, value{&a.BalanceDate, "/", a.Line, a.Index})
+		}
+		for _, r := range append([]*Record{rs.Opening}, rs.Records...) {
+			if r != nil && r.Date != "" {
+				ds = append(ds, value{&r.Date, "D", r.Line, r.Index})
+			}
+		}
+	}
+	return ds
+}
+
+// DateOrder returns the order of day and month in the dates of f, as detected
+// by DetectDateOrder.
+func (f *File) DateOrder() (DateOrder, error) {
+	var ds []string
+	for _, d := range f.dates() {
+		ds = append(ds, *d.s)
+	}
+	return DetectDateOrder(ds)
+}
+
+// NormalizeDates rewrites every date of f, written in the dialect dd, in the
+// Money format that ParseDate reads and FormatDate writes.  This lets the
+// records of files written in different dialects be converted together.  A
+// date read from QIF data that can't be parsed is reported by a ParseError
+// giving the line its record or account header starts on.
+func (f *File) NormalizeDates(dd DateDialect) error {
+	for _, d := range f.dates() {
+		t, err := dd.Parse(*d.s)
+		if err != nil {
+			return f.valueError(d, err)
+		}
+		*d.s = FormatDate(t)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package qif
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateDialect_Parse(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	us := DateDialect{Order: MonthDay}
+	tests := []struct {
+		dialect DateDialect
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "13/04'2006", want: date(2006, time.April, 13)},
+		{in: "11/07/1970", want: date(1970, time.July, 11)},
+		{in: "1/2'98", want: date(1998, time.February, 1)},
+		{in: " 1/ 2'05", want: date(2005, time.February, 1)},
+		{in: "1/2/69", want: date(2069, time.February, 1)},
+		{in: "1/2'69", want: date(2069, time.February, 1)},
+		{in: "1/ 2/05", want: date(2005, time.February, 1)},
+		{in: "1/2'70", want: date(1970, time.February, 1)},
+		{in: "2016-01-02", want: date(2016, time.January, 2)},
+		{dialect: us, in: "2016-01-02", want: date(2016, time.January, 2)},
+		{dialect: us, in: "1/ 2'98", want: date(1998, time.January, 2)},
+		{dialect: us, in: "12/31/1999", want: date(1999, time.December, 31)},
+		{dialect: DateDialect{Pivot: 1930}, in: "1/2'29", want: date(2029, time.February, 1)},
+		{dialect: DateDialect{Pivot: 1930}, in: "1/2'30", want: date(1930, time.February, 1)},
+		{dialect: DateDialect{Pivot: 2000}, in: "1/2'99", want: date(2099, time.February, 1)},
+		{in: "", wantErr: true},
+		{in: "not a date", wantErr: true},
+		{in: "13-04-2006", wantErr: true},
+		{in: "31/02'2016", wantErr: true},
+		{in: "1/2'123", wantErr: true},
+		{in: "1/x/2016", wantErr: true},
+		{dialect: us, in: "13/04'2006", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := test.dialect.Parse(test.in)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("%+v Parse(%q)=_, err? %t want? %t (err=%v)", test.dialect, test.in, gotErr, test.wantErr, err)
+			continue
+		}
+		if err == nil && !got.Equal(test.want) {
+			t.Errorf("%+v Parse(%q)=%v want %v", test.dialect, test.in, got, test.want)
+		}
+	}
+}
+
+func TestDetectDateOrder(t *testing.T) {
+	tests := []struct {
+		dates   []string
+		want    DateOrder
+		wantErr bool
+	}{
+		{want: DayMonth},
+		{dates: []string{"01/02'2016", "2016-12-31"}, want: DayMonth},
+		{dates: []string{"01/02'2016", "13/02'2016"}, want: DayMonth},
+		{dates: []string{"1/ 2'98", "12/31'98", "bad"}, want: MonthDay},
+		{dates: []string{"13/02'2016", "12/31'2016"}, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := DetectDateOrder(test.dates)
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("DetectDateOrder(%q)=_, err? %t want? %t (err=%v)", test.dates, gotErr, test.wantErr, err)
+			continue
+		}
+		if err == nil && got != test.want {
+			t.Errorf("DetectDateOrder(%q)=%v want %v", test.dates, got, test.want)
+		}
+	}
+}
+
+func TestFile_NormalizeDates(t *testing.T) {
+	qif := "!Account\nNVISA\nTCCard\n/1/31'17\n$-10.00\n^\n" +
+		"!Type:CCard\nD12/25'98\nT-5.00\n^\nD1/ 2'17\nT-5.00\n^\n"
+	f, err := ReadFile(strings.NewReader(qif), decoder)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	order, err := f.DateOrder()
+	if err != nil {
+		t.Fatalf("DateOrder() error: %v", err)
+	}
+	if order != MonthDay {
+		t.Errorf("DateOrder()=%v want %v", order, MonthDay)
+	}
+	if err := f.NormalizeDates(DateDialect{Order: order}); err != nil {
+		t.Fatalf("NormalizeDates() error: %v", err)
+	}
+	rs := f.Accounts[0]
+	var got []string
+	for _, r := range rs.Records {
+		got = append(got, r.Date)
+	}
+	if want := []string{"25/12/1998", "02/01'2017"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeDates() record dates=%q want %q", got, want)
+	}
+	if got, want := rs.Account.BalanceDate, "31/01'2017"; got != want {
+		t.Errorf("NormalizeDates() statement date=%q want %q", got, want)
+	}
+	if err := f.NormalizeDates(DateDialect{Order: MonthDay}); err == nil {
+		t.Errorf("NormalizeDates() of normalized dates got no error")
+	}
+}
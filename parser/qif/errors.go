@@ -0,0 +1,92 @@
+package qif
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Causes of the errors returned in reading QIF data, which a ParseError wraps,
+// for use with errors.Is.
+var (
+	// ErrBlankLine is the cause of the error for a blank line, read strictly.
+	ErrBlankLine = errors.New("blank line")
+	// ErrEncoding is the cause of the error for a line that can't be decoded.
+	ErrEncoding = errors.New("character encoding failure")
+	// ErrMalformedSplit is the cause of the error for a split field before
+	// any S line, read strictly.
+	ErrMalformedSplit = errors.New("malformed split: split field before any S line")
+	// ErrUnsupportedField is the cause of the error for a field, or field
+	// value such as an account type, that this package doesn't support.
+	ErrUnsupportedField = errors.New("unsupported field")
+	// ErrMissingHeader is the cause of the error for a record that precedes
+	// any !Type: header.
+	ErrMissingHeader = errors.New("record precedes any !Type: header")
+	// ErrMalformedDate is the cause of the errors of DateDialect.Parse.
+	ErrMalformedDate = errors.New("malformed QIF date")
+	// ErrMalformedAmount is the cause of AmountErrors.
+	ErrMalformedAmount = errors.New("malformed QIF amount")
+)
+
+// ParseError reports a problem reading QIF data, and where in the data it is.
+type ParseError struct {
+	File   string // The Name given in Options, if any.
+	Line   int    // The line number, counting from 1.
+	Record int    // The index of the record, counting from 0.
+	Field  string // The field code of the line, if it has one.
+	Text   string // The line, as read.
+	Err    error  // The cause, such as ErrMalformedSplit.
+}
+
+// Error conforms with error for ParseErrors.
+func (e *ParseError) Error() string {
+	s := "QIF: " + position(e.File, e.Line, e.Record, e.Field) + ": " + e.Err.Error()
+	if e.Text != "" {
+		s += fmt.Sprintf(" (line %q)", e.Text)
+	}
+	return s
+}
+
+// Unwrap returns the cause of e.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// position describes where in QIF data a Warning or ParseError is.
+func position(file string, line, record int, field string) string {
+	s := fmt.Sprintf("line %d, record %d", line, record)
+	if file != "" {
+		s = file + ": " + s
+	}
+	if field != "" {
+		s += fmt.Sprintf(", field %q", field)
+	}
+	return s
+}
+
+// valueError returns a ParseError with cause err for the value v of f, or
+// just err if v wasn't read from QIF data, as for an OFX or CSV file.
+func (f *File) valueError(v value, err error) error {
+	if v.line == 0 {
+		return err
+	}
+	return &ParseError{
+		File:   f.name,
+		Line:   v.line,
+		Record: v.index,
+		Field:  v.field,
+		Err:    err,
+	}
+}
+
+// parseError returns a ParseError with the given field code and cause for the
+// line just scanned by q.
+func (q *QIF) parseError(field string, err error) *ParseError {
+	return &ParseError{
+		File:   q.opts.Name,
+		Line:   q.linesRead,
+		Record: q.recordsRead,
+		Field:  field,
+		Text:   q.text,
+		Err:    err,
+	}
+}
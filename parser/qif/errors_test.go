@@ -0,0 +1,145 @@
+package qif
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+func TestReadFile_parseErrors(t *testing.T) {
+	tests := []struct {
+		desc  string
+		qif   string
+		cause error
+		want  ParseError
+	}{
+		{
+			desc:  "blank line",
+			qif:   "!Type:Bank\nD1/1'17\n\nT-1.00\n^\n",
+			cause: ErrBlankLine,
+			want:  ParseError{File: "bank.qif", Line: 3, Record: 0},
+		},
+		{
+			desc:  "split field before any S line",
+			qif:   "!Type:Bank\nD1/1'17\nT-1.00\n^\nD2/1'17\n$-1.00\n^\n",
+			cause: ErrMalformedSplit,
+			want:  ParseError{File: "bank.qif", Line: 6, Record: 1, Field: "$", Text: "$-1.00"},
+		},
+		{
+			desc:  "unsupported account type",
+			qif:   "!Type:Invoice\nD1/1'17\n^\n",
+			cause: ErrUnsupportedField,
+			want:  ParseError{File: "bank.qif", Line: 1, Record: 0, Field: "!", Text: "!Type:Invoice"},
+		},
+		{
+			desc:  "record before any header",
+			qif:   "D1/1'17\n^\n",
+			cause: ErrMissingHeader,
+			want:  ParseError{File: "bank.qif", Line: 2, Record: 0, Text: "^"},
+		},
+		{
+			desc:  "line too long",
+			qif:   "!Type:Bank\nM" + strings.Repeat("x", 70000) + "\n^\n",
+			cause: bufio.ErrTooLong,
+			want:  ParseError{File: "bank.qif", Line: 2, Record: 0},
+		},
+	}
+	for _, test := range tests {
+		_, err := ReadFileWithOptions(strings.NewReader(test.qif), decoder, Options{Name: "bank.qif"})
+		if !errors.Is(err, test.cause) {
+			t.Errorf("%s: ReadFile()=_, %v want cause %v", test.desc, err, test.cause)
+		}
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: ReadFile()=_, %v want a *ParseError", test.desc, err)
+			continue
+		}
+		got := *pe
+		got.Err = nil
+		if got != test.want {
+			t.Errorf("%s: ReadFile() error=%+v want %+v", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestFile_normalizeErrors(t *testing.T) {
+	qif := "!Account\nNVISA\nTCCard\n/31/02'2016\n^\n" +
+		"!Type:CCard\nD01/01'2016\nT-1.00\n^\nD02/01'2016\nT-12,34\n^\n"
+	tests := []struct {
+		desc      string
+		normalize func(f *File) error
+		cause     error
+		want      ParseError
+	}{
+		{
+			desc:      "statement date",
+			normalize: func(f *File) error { return f.NormalizeDates(DateDialect{}) },
+			cause:     ErrMalformedDate,
+			want:      ParseError{File: "visa.qif", Line: 1, Record: 0, Field: "/"},
+		},
+		{
+			desc:      "record amount",
+			normalize: func(f *File) error { return f.NormalizeAmounts(AmountFormat{}) },
+			cause:     ErrMalformedAmount,
+			want:      ParseError{File: "visa.qif", Line: 10, Record: 2, Field: "T"},
+		},
+	}
+	for _, test := range tests {
+		f, err := ReadFileWithOptions(strings.NewReader(qif), decoder, Options{Name: "visa.qif"})
+		if err != nil {
+			t.Fatalf("ReadFile() error: %v", err)
+		}
+		err = test.normalize(f)
+		if !errors.Is(err, test.cause) {
+			t.Errorf("%s: normalizing=%v want cause %v", test.desc, err, test.cause)
+		}
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: normalizing=%v want a *ParseError", test.desc, err)
+			continue
+		}
+		got := *pe
+		got.Err = nil
+		if got != test.want {
+			t.Errorf("%s: normalizing error=%+v want %+v", test.desc, got, test.want)
+		}
+	}
+}
+
+// failingTransformer fails to transform anything.
+type failingTransformer struct {
+	transform.NopResetter
+}
+
+func (failingTransformer) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	return 0, 0, errors.New("undecodable")
+}
+
+func TestNext_encodingError(t *testing.T) {
+	q := New(strings.NewReader("!Type:Bank\n^\n"), &encoding.Decoder{Transformer: failingTransformer{}})
+	_, err := q.Next()
+	if !errors.Is(err, ErrEncoding) {
+		t.Errorf("Next()=_, %v want cause %v", err, ErrEncoding)
+	}
+}
+
+func TestParseError_Error(t *testing.T) {
+	err := &ParseError{File: "bank.qif", Line: 6, Record: 1, Field: "$", Text: "$-1.00", Err: ErrMalformedSplit}
+	want := `QIF: bank.qif: line 6, record 1, field "$": malformed split: split field before any S line (line "$-1.00")`
+	if got := err.Error(); got != want {
+		t.Errorf("Error()=%q want %q", got, want)
+	}
+}
+
+func TestErrMalformedDateAmount(t *testing.T) {
+	if _, err := (DateDialect{}).Parse("31/02'2016"); !errors.Is(err, ErrMalformedDate) {
+		t.Errorf("Parse()=_, %v want cause %v", err, ErrMalformedDate)
+	}
+	if _, err := (AmountFormat{}).Normalize("12,34"); !errors.Is(err, ErrMalformedAmount) {
+		t.Errorf("Normalize()=_, %v want cause %v", err, ErrMalformedAmount)
+	}
+}
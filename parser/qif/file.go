@@ -0,0 +1,211 @@
+package qif
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+)
+
+// Account holds the attributes of an account read from an !Account header.
+type Account struct {
+	Name        string // The account name.
+	Type        string // The account type, eg. Bank, CCard or Invst.
+	Description string
+	CreditLimit string // Credit limit, for credit card accounts.
+	BalanceDate string // Date of the statement Balance.
+	Balance     string // Statement balance.
+
+	// Where the account's first !Account header was read, if from QIF data,
+	// as for a Record.
+	Line, Index int
+}
+
+// File holds the accounts, and any category and class lists, read from a QIF
+// file that may contain many accounts.
+type File struct {
+	Accounts   []*RecordSet // Accounts in the order they first appear in the file.
+	Categories []*Category
+	Classes    []*Class
+	Warnings   []Warning // Problems tolerated in reading the file in lenient mode.
+
+	name string // The Name given in Options, if any.
+}
+
+// value is a field value of a File, such as a date, and where it was read.
+type value struct {
+	s           *string
+	field       string // The field code.
+	line, index int    // As for a Record.
+}
+
+// accountField stores a field line of an !Account header in r.Account,
+// returning false if the line is not one.
+func accountField(r *Record, spec, rest string) bool {
+	a := r.Account
+	if a == nil {
+		a = &Account{}
+	}
+	switch spec {
+	case "N":
+		a.Name = rest
+	case "T":
+		a.Type = rest
+	case "D":
+		a.Description = rest
+	case "L":
+		a.CreditLimit = rest
+	case "/":
+		a.BalanceDate = rest
+	case "$":
+		a.Balance = rest
+	default:
+		return false
+	}
+	r.Account = a
+	return true
+}
+
+// ReadFile reads every account, category and class from a QIF file.  Each
+// account is introduced by an !Account header and followed by its own !Type:
+// section of records.  The !Account headers between !Option:AutoSwitch and
+// !Clear:AutoSwitch only list accounts, and are not followed by records.  A
+// file with no headers holds a single account, read as for NewRecordSet.
+// Sections of memorised transactions, securities and prices are skipped.
+//
+// Every RecordSet returned has an Opening record.  Where a section doesn't
+// start with an opening balance record, or there is no section at all, one
+// with no amount is supplied.
+func ReadFile(r io.Reader, dec *encoding.Decoder) (*File, error) {
+	return ReadFileWithOptions(r, dec, Options{})
+}
+
+// ReadFileWithOptions reads a QIF file as ReadFile does, but as opts give.
+func ReadFileWithOptions(r io.Reader, dec *encoding.Decoder, opts Options) (*File, error) {
+	q := NewWithOptions(r, dec, opts)
+	f := &File{name: opts.Name}
+	var cur *RecordSet
+	for {
+		rec, err := q.Next()
+		if err == ErrEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case skippedSections[q.section]:
+		case isListSection(q.section):
+			if rec.CategoryEntry != nil {
+				f.Categories = append(f.Categories, rec.CategoryEntry)
+			}
+			if rec.ClassEntry != nil {
+				f.Classes = append(f.Classes, rec.ClassEntry)
+			}
+		case rec.Account != nil:
+			rec.Account.Line, rec.Account.Index = rec.Line, rec.Index
+			rs := f.account(rec.Account)
+			if !q.autoSwitch {
+				cur = rs
+			}
+		case strings.HasPrefix(rec.Type, "Type:"):
+			if err := q.checkAccountType(rec); err != nil {
+				return nil, err
+			}
+			if cur == nil || cur.Opening != nil {
+				// A section with no account header of its own.
+				cur = &RecordSet{}
+				f.Accounts = append(f.Accounts, cur)
+			}
+			cur.startSection(rec)
+		case cur == nil:
+			pe := q.parseError("", ErrMissingHeader)
+			pe.Record--
+			return nil, pe
+		default:
+			cur.Records = append(cur.Records, rec)
+		}
+	}
+	for _, rs := range f.Accounts {
+		if rs.Opening == nil {
+			rs.Opening = &Record{Type: "Type:" + rs.Account.Type, Label: rs.Account.Name, Transfer: true}
+		}
+	}
+	f.Warnings = q.Warnings()
+	return f, nil
+}
+
+// account returns the RecordSet for the account a, adding one to f if a isn't
+// already known.  The attributes of a known account are updated with those
+// given in a.
+func (f *File) account(a *Account) *RecordSet {
+	for _, rs := range f.Accounts {
+		if rs.Account != nil && a.Name != "" && rs.Account.Name == a.Name {
+			rs.Account.merge(a)
+			return rs
+		}
+	}
+	rs := &RecordSet{Account: a}
+	f.Accounts = append(f.Accounts, rs)
+	return rs
+}
+
+// merge copies the attributes set in o to a.
+func (a *Account) merge(o *Account) {
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&a.Type, o.Type},
+		{&a.Description, o.Description},
+		{&a.CreditLimit, o.CreditLimit},
+		{&a.BalanceDate, o.BalanceDate},
+		{&a.Balance, o.Balance},
+	} {
+		if f.src != "" {
+			*f.dst = f.src
+		}
+	}
+}
+
+// startSection stores first, the first record of a !Type: section, as the
+// opening record of rs if it's an opening balance for the account.  Otherwise
+// an empty opening record is supplied instead, and first is kept as the first
+// of the records.
+func (rs *RecordSet) startSection(first *Record) {
+	var name string
+	if rs.Account != nil {
+		name = rs.Account.Name
+	}
+	if isOpening(first, name) {
+		rs.Opening = first
+		return
+	}
+	rs.Opening = &Record{Type: first.Type, Label: name, Transfer: true}
+	rs.Records = append(rs.Records, first)
+}
+
+// isOpening reports whether r is the opening balance record of the named
+// account, or of an account whose name isn't known if name is "".
+func isOpening(r *Record, name string) bool {
+	return r.Payee == "Opening Balance" || r.Transfer && name != "" && r.Label == name
+}
+
+// checkAccountType returns a *ParseError for the ! line of r, the record just
+// read by q, unless r starts a section of records of an account type that this
+// package supports.
+func (q *QIF) checkAccountType(r *Record) error {
+	switch r.Type {
+	case "Type:Bank", "Type:Cash", "Type:CCard", "Type:Invst", "Type:Oth A", "Type:Oth L":
+		return nil
+	}
+	return &ParseError{
+		File:   q.opts.Name,
+		Line:   q.typeLine,
+		Record: q.recordsRead - 1,
+		Field:  "!",
+		Text:   "!" + r.Type,
+		Err:    fmt.Errorf("%w: account type %q, want \"Type:Bank\", \"Type:CCard\", \"Type:Cash\", \"Type:Invst\", \"Type:Oth A\" or \"Type:Oth L\"", ErrUnsupportedField, r.Type),
+	}
+}
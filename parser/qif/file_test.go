@@ -0,0 +1,320 @@
+package qif
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadFile(t *testing.T) {
+	tests := []struct {
+		desc    string
+		qif     string
+		want    *File
+		wantErr bool
+	}{
+		{
+			desc: "empty",
+			want: &File{},
+		},
+		{
+			desc: "single account without header",
+			qif: `!Type:Bank
+D01/01'2016
+T100.00
+POpening Balance
+L[Current]
+^
+D02/01'2016
+T-10.00
+PShop
+^
+`,
+			want: &File{Accounts: []*RecordSet{
+				{
+					Opening: &Record{Type: "Type:Bank", Date: "01/01'2016", Amount: "100.00", Payee: "Opening Balance", Label: "Current", Transfer: true, Line: 1},
+					Records: []*Record{{Date: "02/01'2016", Amount: "-10.00", Payee: "Shop", Line: 7, Index: 1}},
+				},
+			}},
+		},
+		{
+			desc: "investment account without header or opening balance",
+			qif: `!Type:Invst
+D01/01'2016
+NBuy
+YACME
+I2.50
+Q10
+T25.00
+^
+D02/01'2016
+NDiv
+YACME
+T1.00
+^
+`,
+			want: &File{Accounts: []*RecordSet{
+				{
+					Opening: &Record{Type: "Type:Invst", Transfer: true},
+					Records: []*Record{
+						{Type: "Type:Invst", Date: "01/01'2016", Action: "Buy", Security: "ACME", Price: "2.50", Quantity: "10", Amount: "25.00", Line: 1},
+						{Date: "02/01'2016", Action: "Div", Security: "ACME", Amount: "1.00", Line: 9, Index: 1},
+					},
+				},
+			}},
+		},
+		{
+			desc: "account list then accounts with records",
+			qif: `!Option:AutoSwitch
+!Account
+NCurrent
+TBank
+DDay to day
+^
+NVISA
+TCCard
+L2,500.00
+/31/01'2016
+$-123.45
+^
+NSavings
+TBank
+^
+!Clear:AutoSwitch
+!Account
+NCurrent
+TBank
+^
+!Type:Bank
+D01/01'2016
+T100.00
+POpening Balance
+L[Current]
+^
+D02/01'2016
+T-10.00
+PShop
+^
+!Account
+NVISA
+TCCard
+^
+!Type:CCard
+D03/01'2016
+T-20.00
+PGarage
+^
+`,
+			want: &File{Accounts: []*RecordSet{
+				{
+					Account: &Account{Name: "Current", Type: "Bank", Description: "Day to day", Line: 1},
+					Opening: &Record{Type: "Type:Bank", Date: "01/01'2016", Amount: "100.00", Payee: "Opening Balance", Label: "Current", Transfer: true, Line: 21, Index: 4},
+					Records: []*Record{{Date: "02/01'2016", Amount: "-10.00", Payee: "Shop", Line: 27, Index: 5}},
+				},
+				{
+					Account: &Account{Name: "VISA", Type: "CCard", CreditLimit: "2,500.00", BalanceDate: "31/01'2016", Balance: "-123.45", Line: 7, Index: 1},
+					Opening: &Record{Type: "Type:CCard", Label: "VISA", Transfer: true},
+					Records: []*Record{{Type: "Type:CCard", Date: "03/01'2016", Amount: "-20.00", Payee: "Garage", Line: 35, Index: 7}},
+				},
+				{
+					Account: &Account{Name: "Savings", Type: "Bank", Line: 13, Index: 2},
+					Opening: &Record{Type: "Type:Bank", Label: "Savings", Transfer: true},
+				},
+			}},
+		},
+		{
+			desc: "category and class lists",
+			qif: `!Type:Class
+NHoliday2019
+DSummer holiday
+^
+!Type:Cat
+NFood
+DFood and drink
+E
+B200.00
+^
+NFood:Groceries
+E
+^
+NSalary
+I
+T
+RW-2
+^
+!Type:Bank
+D01/01'2016
+T100.00
+POpening Balance
+L[Current]
+^
+`,
+			want: &File{
+				Accounts: []*RecordSet{
+					{Opening: &Record{Type: "Type:Bank", Date: "01/01'2016", Amount: "100.00", Payee: "Opening Balance", Label: "Current", Transfer: true, Line: 19, Index: 4}},
+				},
+				Categories: []*Category{
+					{Name: "Food", Description: "Food and drink", Expense: true, Budget: "200.00"},
+					{Name: "Food:Groceries", Expense: true},
+					{Name: "Salary", Income: true, Tax: true, TaxSchedule: "W-2"},
+				},
+				Classes: []*Class{{Name: "Holiday2019", Description: "Summer holiday"}},
+			},
+		},
+		{
+			desc: "memorised transaction, security and price sections skipped",
+			qif: `!Type:Memorized
+KP
+T-10.00
+PShop
+^
+!Account
+NCurrent
+TBank
+^
+!Type:Bank
+D02/01'2016
+T-10.00
+^
+!Type:Security
+NVanguard FTSE All-World
+SVWRL
+TStock
+^
+!Type:Prices
+"VWRL",50.25," 2/ 1'16"
+^
+`,
+			want: &File{Accounts: []*RecordSet{
+				{
+					Account: &Account{Name: "Current", Type: "Bank", Line: 6, Index: 1},
+					Opening: &Record{Type: "Type:Bank", Label: "Current", Transfer: true},
+					Records: []*Record{{Type: "Type:Bank", Date: "02/01'2016", Amount: "-10.00", Line: 10, Index: 2}},
+				},
+			}},
+		},
+		{
+			desc: "unsupported section",
+			qif: `!Type:Invoice
+^
+`,
+			wantErr: true,
+		},
+		{
+			desc: "record outside a section",
+			qif: `D01/01'2016
+^
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := ReadFile(strings.NewReader(test.qif), decoder)
+			if gotErr := err != nil; gotErr != test.wantErr {
+				t.Fatalf("ReadFile()=_, err? %t want? %t (err=%v)", gotErr, test.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got.Categories, test.want.Categories) {
+				t.Errorf("ReadFile().Categories=%+v want %+v", got.Categories, test.want.Categories)
+			}
+			if !reflect.DeepEqual(got.Classes, test.want.Classes) {
+				t.Errorf("ReadFile().Classes=%+v want %+v", got.Classes, test.want.Classes)
+			}
+			if len(got.Accounts) != len(test.want.Accounts) {
+				t.Fatalf("ReadFile() got %d accounts want %d", len(got.Accounts), len(test.want.Accounts))
+			}
+			for i, rs := range got.Accounts {
+				if want := test.want.Accounts[i]; !reflect.DeepEqual(rs, want) {
+					t.Errorf("ReadFile() account %d=%+v want %+v", i, rs, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAccountName(t *testing.T) {
+	tests := []struct {
+		desc string
+		rs   *RecordSet
+		want string
+	}{
+		{
+			desc: "from opening record",
+			rs:   &RecordSet{Opening: &Record{Label: "Paul - smile Current", Transfer: true}},
+			want: "Paul - smile Current",
+		},
+		{
+			desc: "from account header",
+			rs:   &RecordSet{Account: &Account{Name: "VISA"}, Opening: &Record{Label: "Other"}},
+			want: "VISA",
+		},
+	}
+	for _, test := range tests {
+		if got := test.rs.AccountName(); got != test.want {
+			t.Errorf("%s: AccountName()=%q want %q", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestNewRecordSet_skipsLists(t *testing.T) {
+	in := `!Type:Cat
+NFood
+E
+^
+!Type:Bank
+D01/01'2016
+T100.00
+POpening Balance
+L[Current]
+^
+D02/01'2016
+T-10.00
+^
+`
+	rs, err := NewRecordSet(strings.NewReader(in), decoder)
+	if err != nil {
+		t.Fatalf("NewRecordSet() error: %v", err)
+	}
+	if got, want := rs.AccountName(), "Current"; got != want {
+		t.Errorf("NewRecordSet().AccountName()=%q want %q", got, want)
+	}
+	if got, want := len(rs.Records), 1; got != want {
+		t.Errorf("NewRecordSet() got %d records want %d", got, want)
+	}
+}
+
+func TestReadFileWithOptions(t *testing.T) {
+	in := "!Account\nNCurrent\nTBank\n^\n\n!Type:Bank\nD02/01'2016\nT-10.00\n^\n"
+	if _, err := ReadFile(strings.NewReader(in), decoder); err == nil {
+		t.Errorf("ReadFile() with a blank line got no error")
+	}
+	f, err := ReadFileWithOptions(strings.NewReader(in), decoder, Options{Name: "current.qif", Lenient: true})
+	if err != nil {
+		t.Fatalf("ReadFileWithOptions() error: %v", err)
+	}
+	if got, want := len(f.Accounts[0].Records), 1; got != want {
+		t.Errorf("ReadFileWithOptions() got %d records want %d", got, want)
+	}
+	want := []Warning{{File: "current.qif", Line: 5, Record: 1, Msg: "blank line ignored"}}
+	if !reflect.DeepEqual(f.Warnings, want) {
+		t.Errorf("ReadFileWithOptions().Warnings=%+v want %+v", f.Warnings, want)
+	}
+}
+
+func TestReadFileWithOptions_skippedSections(t *testing.T) {
+	in := "!Type:Prices\n\"VWRL\",50.25,\" 2/ 1'16\"\n^\n!Type:Bank\nD02/01'2016\nT-10.00\n^\n"
+	f, err := ReadFileWithOptions(strings.NewReader(in), decoder, Options{Lenient: true})
+	if err != nil {
+		t.Fatalf("ReadFileWithOptions() error: %v", err)
+	}
+	if len(f.Warnings) != 0 {
+		t.Errorf("ReadFileWithOptions().Warnings=%+v want none", f.Warnings)
+	}
+	if got, want := len(f.Accounts), 1; got != want {
+		t.Errorf("ReadFileWithOptions() got %d accounts want %d", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package qif
+
+// Category is an entry read from a !Type:Cat category list.
+type Category struct {
+	Name        string // The category name, eg. Food:Groceries.
+	Description string
+	Income      bool   // Whether this is an income category.
+	Expense     bool   // Whether this is an expense category.
+	Tax         bool   // Whether this category is tax-related.
+	TaxSchedule string // The tax schedule the category is reported on.
+	Budget      string // Budgeted amount.
+}
+
+// Class is an entry read from a !Type:Class class list.
+type Class struct {
+	Name        string
+	Description string
+}
+
+// isListSection reports whether a section holds a category or class list,
+// rather than an account's records.
+func isListSection(section string) bool {
+	return section == "Type:Cat" || section == "Type:Class"
+}
+
+// skippedSections are the sections of records that aren't read: memorised
+// transactions, securities and security prices, which Money and Quicken
+// include in exports of many accounts.
+var skippedSections = map[string]bool{
+	"Type:Memorized": true,
+	"Type:Security":  true,
+	"Type:Prices":    true,
+}
+
+// isAccountSection reports whether a section holds an account's records,
+// rather than a list or a section that's skipped.
+func isAccountSection(section string) bool {
+	return !isListSection(section) && !skippedSections[section]
+}
+
+// categoryField stores a field line of a category list in r.CategoryEntry,
+// returning false if the line is not one.
+func categoryField(r *Record, spec, rest string) bool {
+	c := r.CategoryEntry
+	if c == nil {
+		c = &Category{}
+	}
+	switch spec {
+	case "N":
+		c.Name = rest
+	case "D":
+		c.Description = rest
+	case "I":
+		c.Income = true
+	case "E":
+		c.Expense = true
+	case "T":
+		c.Tax = true
+	case "R":
+		c.TaxSchedule = rest
+	case "B":
+		c.Budget = rest
+	default:
+		return false
+	}
+	r.CategoryEntry = c
+	return true
+}
+
+// classField stores a field line of a class list in r.ClassEntry, returning
+// false if the line is not one.
+func classField(r *Record, spec, rest string) bool {
+	c := r.ClassEntry
+	if c == nil {
+		c = &Class{}
+	}
+	switch spec {
+	case "N":
+		c.Name = rest
+	case "D":
+		c.Description = rest
+	default:
+		return false
+	}
+	r.ClassEntry = c
+	return true
+}
@@ -1,4 +1,4 @@
-// Package qif contains functions to parse transaction data presented in the QIF format.
+// Package qif contains functions to parse, and write, transaction data presented in the QIF format.
 package qif
 
 import (
@@ -6,17 +6,52 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
+	"unicode"
 
 	"golang.org/x/text/encoding"
 )
 
 // QIF contains the scan state for a set of records in QIF format.
 type QIF struct {
-	scanner   *bufio.Scanner
-	decoder   *encoding.Decoder
-	linesRead int
-	parseErr  error
+	scanner     *bufio.Scanner
+	decoder     *encoding.Decoder
+	opts        Options
+	linesRead   int
+	recordsRead int
+	text        string // The line just scanned, as read.
+	typeLine    int    // The line number of the most recent ! line setting a Record Type.
+	parseErr    error
+	warnings    []Warning
+	section     string // The most recent "Account" or "Type:..." header, which determines how fields are read.
+	autoSwitch  bool   // Whether Account records form a list, rather than introduce a section of records.
+}
+
+// Options give how QIF data is read.  The zero Options read it strictly.
+type Options struct {
+	Name string // The name of the file read, given in Warnings.
+
+	// Lenient is whether to tolerate blank lines, whitespace before field
+	// codes and split fields before any S line, and to report them and
+	// unknown fields as Warnings, rather than fail on the first three and
+	// ignore the last.  Whitespace after field values is also removed, with a
+	// Warning.  Lines may end in CRLF whether or not reading is Lenient.
+	Lenient bool
+}
+
+// Warning describes a problem with QIF data read in lenient mode.
+type Warning struct {
+	File   string // The Name given in Options, if any.
+	Line   int    // The line number, counting from 1.
+	Record int    // The index of the record, counting from 0.
+	Field  string // The field code of the line, if it has one.
+	Msg    string
+}
+
+// String conforms with Stringer for Warnings.
+func (w Warning) String() string {
+	return position(w.File, w.Line, w.Record, w.Field) + ": " + w.Msg
 }
 
 // Record groups the QIF attributes for a single transaction read in QIF format.
@@ -25,24 +60,45 @@ type Record struct {
 	Date     string
 	Amount   string
 	Number   string
+	ID       string // A unique ID of the transaction, such as an OFX FITID, by which duplicates are spotted.
 	Cleared  string
 	Payee    string
 	Label    string
+	Class    string // The class given after the Label, eg. Holiday:France for "Travel/Holiday:France".
 	Memo     string
 	Splits   []*Split
 	Transfer bool
+	Account  *Account // Set only for the Account header records that describe an account.
+
+	CategoryEntry *Category // Set only for records in a Type:Cat category list.
+	ClassEntry    *Class    // Set only for records in a Type:Class class list.
+
+	// Fields only found in investment (Type:Invst) records.
+	Action         string // Investment action, eg. Buy, Sell, Div.
+	Security       string // Security name.
+	Price          string // Price per share.
+	Quantity       string // Number of shares.
+	Commission     string // Commission cost.
+	TransferAmount string // Amount transferred to or from the Label account.
+
+	// Where the record was read, if from QIF data: the line it starts on,
+	// counting from 1, and its index among the records read, counting from 0.
+	Line, Index int
 }
 
 // Split represents a single sub-transaction in a QIF Record that has >1 split.
 type Split struct {
 	Category string
+	Transfer bool   // Whether the Category is an account, given in [ ].
+	Class    string // The class given after the Category.
 	Memo     string
 	Amount   string
-	Percent  string // exists in QIF spec but not supported yet.
+	Percent  string // Percentage of the Record Amount, used in place of Amount.
 }
 
 // RecordSet is a group of QIF Records, with the opening Record separated.
 type RecordSet struct {
+	Account *Account // The account header for the Records, if the file had one.
 	Opening *Record
 	Records []*Record
 }
@@ -53,86 +109,64 @@ func (r *Record) String() string {
 		r.Type, r.Date, r.Amount, r.Number, r.Cleared, r.Payee, r.Label, r.Memo)
 }
 
-// New returns a QIF scanner for QIF data to be read from the given io.Reader.
+// New returns a QIF scanner for QIF data to be read strictly from the given
+// io.Reader.
 func New(qifData io.Reader, dec *encoding.Decoder) *QIF {
-	return &QIF{scanner: bufio.NewScanner(qifData), decoder: dec}
+	return NewWithOptions(qifData, dec, Options{})
+}
+
+// NewWithOptions returns a QIF scanner for QIF data to be read from the given
+// io.Reader as opts give.
+func NewWithOptions(qifData io.Reader, dec *encoding.Decoder, opts Options) *QIF {
+	return &QIF{scanner: bufio.NewScanner(qifData), decoder: dec, opts: opts}
+}
+
+// Warnings returns the Warnings about the QIF data read so far in lenient mode.
+func (q *QIF) Warnings() []Warning {
+	return q.warnings
 }
 
 // ErrEOF is a condition used to signal that the parser reached the end of a QIF file.
 var ErrEOF = errors.New("QIF end of file")
 
-// ErrNotSupported is returned if a QIF field type is encountered that this parser
-// doesn't support.
+// ErrNotSupported describes a QIF field type that this parser doesn't support.
+//
+// Deprecated: it's no longer returned.  Unsupported fields are reported by a
+// *ParseError whose cause is ErrUnsupportedField.
 type ErrNotSupported struct {
 	Desc string
 }
 
+// Error conforms with error for ErrNotSupported.
 func (e *ErrNotSupported) Error() string {
 	return fmt.Sprintf("QIF: %q not supported.", e.Desc)
 }
 
-// Next is an iterator function that returns the next Record scanned from the QIF file.
+// Next is an iterator function that returns the next Record scanned from the
+// QIF file.  Errors in reading the file are returned as *ParseErrors.
 func (q *QIF) Next() (*Record, error) {
 	r := &Record{}
 	var s *Split
 	for q.scanner.Scan() {
-		line, err := q.scanner.Text(), q.scanner.Err()
 		q.linesRead++
+		line, err := q.line()
 		if err != nil {
-			return nil, fmt.Errorf("QIF: scanner error at line %d: %v", q.linesRead, err)
+			return nil, err
 		}
 		if len(line) == 0 {
-			return nil, fmt.Errorf("QIF: empty line at line %d", q.linesRead)
+			// A blank line, tolerated in lenient mode.
+			continue
 		}
-		utf8Line, err := q.decoder.String(line)
-		if err != nil {
-			return nil, fmt.Errorf("QIF: encoding.Decoder.String(%v): err %v", line, err)
+		if r.Line == 0 {
+			r.Line, r.Index = q.linesRead, q.recordsRead
 		}
-		switch spec, rest := utf8Line[0:1], utf8Line[1:]; spec {
-		case "!":
-			// 'Type' line
-			r.Type = rest
-		case "D":
-			// Date line
-			r.Date = rest
-		case "T", "U":
-			// Transaction amount line
-			r.Amount = rest
-		case "N":
-			// Check number line, or other identifier eg. ATM
-			r.Number = rest
-		case "C":
-			// Cleared status line
-			r.Cleared = rest
-		case "P":
-			// Payee line
-			r.Payee = rest
-		case "L":
-			// Label (category) line
-			r.Label, r.Transfer = sanitizeLabel(rest)
-		case "M":
-			// Memo (description) line
-			r.Memo = rest
-		case "S":
-			// Split: Category line
-			if s != nil {
-				r.Splits = append(r.Splits, s)
-			}
-			s = &Split{Category: rest}
-		case "E":
-			// Split: Memo line - we assume the Split opened with 'S'.
-			s.Memo = rest
-		case "$":
-			// Split: Amount line - we assume the Split opened with 'S'
-			s.Amount = rest
-		case "%":
-			// Split: percentage - used in place of Amount. Not supported.
-			q.parseErr = &ErrNotSupported{Desc: "Field %"}
-		case "^":
+		spec, rest := line[0:1], line[1:]
+		if spec == "^" {
 			// Record separator line. Store Split if one is in progress.
 			if s != nil {
 				r.Splits = append(r.Splits, s)
 			}
+			q.recordsRead++
 			if q.parseErr != nil {
 				e := q.parseErr
 				q.parseErr = nil
@@ -140,65 +174,266 @@ func (q *QIF) Next() (*Record, error) {
 			}
 			return r, nil
 		}
+		if skippedSections[q.section] && spec != "!" {
+			continue
+		}
+		if q.section == "Account" && accountField(r, spec, rest) {
+			continue
+		}
+		if q.section == "Type:Invst" && investmentField(r, spec, rest) {
+			continue
+		}
+		if q.section == "Type:Cat" && categoryField(r, spec, rest) {
+			continue
+		}
+		if q.section == "Type:Class" && classField(r, spec, rest) {
+			continue
+		}
+		s = q.field(r, s, spec, rest)
+	}
+	if err := q.scanner.Err(); err != nil {
+		// The error is with the line after the last one scanned.
+		q.text = ""
+		pe := q.parseError("", err)
+		pe.Line++
+		return nil, pe
 	}
 	return nil, ErrEOF
 }
 
+// line returns the line just scanned, decoded to UTF-8.  In lenient mode any
+// whitespace before the field code or after the field value is removed, and a
+// blank line is returned empty, each with a Warning.
+func (q *QIF) line() (string, error) {
+	line := q.scanner.Text()
+	q.text = line
+	if len(line) == 0 && !q.opts.Lenient {
+		return "", q.parseError("", ErrBlankLine)
+	}
+	utf8Line, err := q.decoder.String(line)
+	if err != nil {
+		return "", q.parseError("", fmt.Errorf("%w: %v", ErrEncoding, err))
+	}
+	if !q.opts.Lenient {
+		return utf8Line, nil
+	}
+	trimmed := strings.TrimLeftFunc(utf8Line, unicode.IsSpace)
+	switch {
+	case trimmed == "":
+		q.warn("", "blank line ignored")
+	case trimmed != utf8Line:
+		q.warn(trimmed[0:1], "whitespace before field code ignored")
+	}
+	if t := strings.TrimRightFunc(trimmed, unicode.IsSpace); t != trimmed {
+		q.warn(t[0:1], "whitespace after field value ignored")
+		trimmed = t
+	}
+	return trimmed, nil
+}
+
+// warn adds a Warning about the line just scanned, which has the given field
+// code.
+func (q *QIF) warn(field, msg string) {
+	q.warnings = append(q.warnings, Warning{
+		File:   q.opts.Name,
+		Line:   q.linesRead,
+		Record: q.recordsRead,
+		Field:  field,
+		Msg:    msg,
+	})
+}
+
+// split returns s, the Split in progress, for a split field line with the
+// given field code.  If there is none, as when the line precedes any S line,
+// that's an error, or in lenient mode a Warning, and a Split with no category
+// is started.
+func (q *QIF) split(s *Split, spec string) *Split {
+	if s != nil {
+		return s
+	}
+	if q.opts.Lenient {
+		q.warn(spec, "split field before any S line; split with no category started")
+	} else if q.parseErr == nil {
+		q.parseErr = q.parseError(spec, ErrMalformedSplit)
+	}
+	return &Split{}
+}
+
+// field stores a non-investment field line in r, or in the Split in progress.
+// It returns the Split in progress after the line is processed.
+func (q *QIF) field(r *Record, s *Split, spec, rest string) *Split {
+	switch spec {
+	case "!":
+		// 'Type' line, or an option that applies to the lines that follow.
+		switch rest {
+		case "Option:AutoSwitch":
+			q.autoSwitch = true
+		case "Clear:AutoSwitch":
+			q.autoSwitch = false
+		default:
+			r.Type = rest
+			q.typeLine = q.linesRead
+			if rest == "Account" || strings.HasPrefix(rest, "Type:") {
+				q.section = rest
+			}
+		}
+	case "D":
+		// Date line
+		r.Date = rest
+	case "T", "U":
+		// Transaction amount line
+		r.Amount = rest
+	case "N":
+		// Check number line, or other identifier eg. ATM
+		r.Number = rest
+	case "C":
+		// Cleared status line
+		r.Cleared = rest
+	case "P":
+		// Payee line
+		r.Payee = rest
+	case "L":
+		// Label (category) line
+		label, class := splitClass(rest)
+		r.Label, r.Transfer = sanitizeLabel(label)
+		r.Class = class
+	case "M":
+		// Memo (description) line
+		r.Memo = rest
+	case "S":
+		// Split: Category line
+		if s != nil {
+			r.Splits = append(r.Splits, s)
+		}
+		s = &Split{}
+		category, class := splitClass(rest)
+		s.Category, s.Transfer = sanitizeLabel(category)
+		s.Class = class
+	case "E":
+		// Split: Memo line
+		s = q.split(s, spec)
+		s.Memo = rest
+	case "$":
+		// Split: Amount line
+		s = q.split(s, spec)
+		s.Amount = rest
+	case "%":
+		// Split: percentage of the record amount - used in place of Amount.
+		s = q.split(s, spec)
+		s.Percent = rest
+	case "A", "F", "X":
+		// Address, reimbursable expense flag and small business extension
+		// lines, which aren't read.
+	default:
+		if q.opts.Lenient {
+			q.warn(spec, "unknown field ignored")
+		}
+	}
+	return s
+}
+
+// investmentField stores a field line specific to investment records in r,
+// returning false if the line is not one.
+func investmentField(r *Record, spec, rest string) bool {
+	switch spec {
+	case "N":
+		// Action line, eg. Buy, Sell, Div.
+		r.Action = rest
+	case "Y":
+		// Security name line
+		r.Security = rest
+	case "I":
+		// Price per share line
+		r.Price = rest
+	case "Q":
+		// Quantity of shares line
+		r.Quantity = rest
+	case "O":
+		// Commission line
+		r.Commission = rest
+	case "$":
+		// Amount transferred line
+		r.TransferAmount = rest
+	default:
+		return false
+	}
+	return true
+}
+
 // NewRecordSet returns a RecordSet for QIF records read from the given io.Reader.
-// Character set conversion from input to UTF-8 is performed by dec.
+// Character set conversion from input to UTF-8 is performed by dec.  Errors in
+// reading the records are returned as *ParseErrors.
 func NewRecordSet(r io.Reader, dec *encoding.Decoder) (*RecordSet, error) {
 	q := New(r, dec)
 	first, err := q.Next()
+	for err == nil && !isAccountSection(q.section) {
+		// Skip any lists or skipped sections preceding the account's records.
+		first, err = q.Next()
+	}
 	if err != nil {
-		return nil, fmt.Errorf("reading first QIF record, error: %v", err)
+		return nil, err
 	}
-	switch first.Type {
-		case "Type:Bank":
-	  case "Type:Cash":
-		case "Type:CCard":
-			break
-		default:
-			return nil, fmt.Errorf("unsupported first record type: got %q want \"Type:Bank\", \"Type:CCard\" or \"Type:Cash\", (record: %v)", first.Type, first)
+	if err := q.checkAccountType(first); err != nil {
+		return nil, err
 	}
-	rs := &RecordSet{Opening: first}
-	cnt := 0
+	rs := &RecordSet{}
+	rs.startSection(first)
 	for {
-		cnt++
 		r, err := q.Next()
 		if err == ErrEOF {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("reading QIF record %d, error: %v", cnt, err)
+			return nil, err
+		}
+		if !isAccountSection(q.section) {
+			continue
 		}
 		rs.Records = append(rs.Records, r)
 	}
 	return rs, nil
 }
 
-// AccountName returns the name of the account described by the opening record of the RecordSet.
+// AccountName returns the name of the account described by the account header
+// of the RecordSet, or failing that by its opening record.
 func (rs *RecordSet) AccountName() string {
-	n := rs.Opening.Label
-	return n[1 : len(n)-1]
+	if rs.Account != nil && rs.Account.Name != "" {
+		return rs.Account.Name
+	}
+	return rs.Opening.Label
 }
 
 // ParseDate parses date strings in the QIF format used by Microsoft Money 2000,
-// which is dd/mm'yyyy or dd/mm/yyyy for pre-2000 dates.
+// which is dd/mm'yyyy or dd/mm/yyyy for pre-2000 dates.  Other day-first
+// dates are read as by the zero DateDialect.
 func ParseDate(d string) (time.Time, error) {
-	t, err := time.Parse("02/01'2006", d)
-	if err != nil {
-		if t, err = time.Parse("02/01/2006", d); err != nil {
-			return time.Time{}, err
-		}
+	return DateDialect{}.Parse(d)
+}
+
+// FormatDate formats t in the QIF format used by Microsoft Money 2000, the
+// reverse of ParseDate.
+func FormatDate(t time.Time) string {
+	if t.Year() < 2000 {
+		return t.Format("02/01/2006")
+	}
+	return t.Format("02/01'2006")
+}
+
+// splitClass splits a QIF category, written Category/Class or
+// Category/Class:Subclass, into the category and the class.  The class is
+// empty if there isn't one.
+func splitClass(l string) (string, string) {
+	if i := strings.IndexByte(l, '/'); i >= 0 {
+		return l[:i], l[i+1:]
 	}
-	return t, nil
+	return l, ""
 }
 
 // sanitizeLabel strips wrapping [ ] on label.  If present returns the stripped
 // label and true; otherwise the original label and false.
 func sanitizeLabel(l string) (string, bool) {
-	if len(l) >= 2 && l[0] == '[' && l[len(l) - 1] == ']' {
-		return l[1:len(l) - 1], true
+	if len(l) >= 2 && l[0] == '[' && l[len(l)-1] == ']' {
+		return l[1 : len(l)-1], true
 	}
 	return l, false
 }
@@ -27,14 +27,14 @@ func TestNext(t *testing.T) {
 		{
 			desc:     "empty record",
 			qif:      `^`,
-			wantRecs: []*Record{{}},
+			wantRecs: []*Record{{Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
 		{
 			desc:     "text following record separator is ignored",
 			qif:      `^ignored`,
-			wantRecs: []*Record{{}},
+			wantRecs: []*Record{{Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -43,7 +43,7 @@ func TestNext(t *testing.T) {
 			qif: `^
 ^
 ^`,
-			wantRecs: []*Record{{}, {}, {}},
+			wantRecs: []*Record{{Line: 1}, {Line: 2, Index: 1}, {Line: 3, Index: 2}},
 			wantErrs: []bool{false, false, false},
 			wantEOF:  true,
 		},
@@ -59,7 +59,7 @@ D15/03'2003
 			qif: `!Type:Foo
 ^
 `,
-			wantRecs: []*Record{{Type: "Type:Foo"}},
+			wantRecs: []*Record{{Type: "Type:Foo", Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -68,7 +68,14 @@ D15/03'2003
 			qif: `D15/03'2003
 ^
 `,
-			wantRecs: []*Record{{Date: "15/03'2003"}},
+			wantRecs: []*Record{{Date: "15/03'2003", Line: 1}},
+			wantErrs: []bool{false},
+			wantEOF:  true,
+		},
+		{
+			desc:     "CRLF line endings",
+			qif:      "D15/03'2003\r\n^\r\n",
+			wantRecs: []*Record{{Date: "15/03'2003", Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -77,7 +84,7 @@ D15/03'2003
 			qif: `D01/02/1996
 ^
 `,
-			wantRecs: []*Record{{Date: "01/02/1996"}},
+			wantRecs: []*Record{{Date: "01/02/1996", Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -86,7 +93,7 @@ D15/03'2003
 			qif: `T10.00
 ^
 `,
-			wantRecs: []*Record{{Amount: "10.00"}},
+			wantRecs: []*Record{{Amount: "10.00", Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -95,7 +102,7 @@ D15/03'2003
 			qif: `U10.00
 ^
 `,
-			wantRecs: []*Record{{Amount: "10.00"}},
+			wantRecs: []*Record{{Amount: "10.00", Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -104,7 +111,7 @@ D15/03'2003
 			qif: `N123456
 ^
 `,
-			wantRecs: []*Record{{Number: "123456"}},
+			wantRecs: []*Record{{Number: "123456", Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -113,7 +120,7 @@ D15/03'2003
 			qif: `CX
 ^
 `,
-			wantRecs: []*Record{{Cleared: "X"}},
+			wantRecs: []*Record{{Cleared: "X", Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -122,7 +129,7 @@ D15/03'2003
 			qif: `PJohn Lewis
 ^
 `,
-			wantRecs: []*Record{{Payee: "John Lewis"}},
+			wantRecs: []*Record{{Payee: "John Lewis", Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -131,7 +138,7 @@ D15/03'2003
 			qif: `LFood:Groceries
 ^
 `,
-			wantRecs: []*Record{{Label: "Food:Groceries"}},
+			wantRecs: []*Record{{Label: "Food:Groceries", Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -140,7 +147,7 @@ D15/03'2003
 			qif: `L[Paul_-_smile_current]
 ^
 `,
-			wantRecs: []*Record{{Label: "Paul_-_smile_current", Transfer: true}},
+			wantRecs: []*Record{{Label: "Paul_-_smile_current", Transfer: true, Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -149,7 +156,7 @@ D15/03'2003
 			qif: `MShopping
 ^
 `,
-			wantRecs: []*Record{{Memo: "Shopping"}},
+			wantRecs: []*Record{{Memo: "Shopping", Line: 1}},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
@@ -165,7 +172,7 @@ LHousing:Improvements
 ^
 `,
 			wantRecs: []*Record{
-				{Date: "15/03'2003", Amount: "-26.07", Number: "VISA", Cleared: "X", Payee: "Homebase", Label: "Housing:Improvements", Memo: "Paint"},
+				{Date: "15/03'2003", Amount: "-26.07", Number: "VISA", Cleared: "X", Payee: "Homebase", Label: "Housing:Improvements", Memo: "Paint", Line: 1},
 			},
 			wantErrs: []bool{false},
 			wantEOF:  true,
@@ -196,6 +203,7 @@ $-4.40
 					Payee:   "The Bridge Bar",
 					Label:   "Food:Dining Out",
 					Memo:    "Lunch/early dinner at Heathrow for me and R",
+					Line:    1,
 					Splits: []*Split{
 						{
 							Category: "Food:Dining Out",
@@ -214,16 +222,41 @@ $-4.40
 			wantEOF:  true,
 		},
 		{
-			desc: "unsupported Split percentage field",
+			desc: "Split transfer field",
+			qif: `D24/11'2004
+T-100.00
+S[Savings]/Holiday
+$-100.00
+^
+`,
+			wantRecs: []*Record{
+				{
+					Date:   "24/11'2004",
+					Amount: "-100.00",
+					Splits: []*Split{{Category: "Savings", Transfer: true, Class: "Holiday", Amount: "-100.00"}},
+					Line:   1,
+				},
+			},
+			wantErrs: []bool{false},
+			wantEOF:  true,
+		},
+		{
+			desc: "Split percentage field",
 			qif: `D24/11'2004
 SFood:Dining Out
 ELunch/early dinner
 %25.00
 ^
 `,
+			wantRecs: []*Record{
+				{
+					Date:   "24/11'2004",
+					Splits: []*Split{{Category: "Food:Dining Out", Memo: "Lunch/early dinner", Percent: "25.00"}},
+					Line:   1,
+				},
+			},
+			wantErrs: []bool{false},
 			wantEOF:  true,
-			wantRecs: []*Record{nil},
-			wantErrs: []bool{true},
 		},
 		{
 			desc: "funds transferred in",
@@ -236,7 +269,7 @@ L[Joint - smile Current]
 ^
 `,
 			wantRecs: []*Record{
-				{Date: "28/11'2011", Amount: "800.00", Number: "", Cleared: "X", Payee: "Us", Label: "Joint - smile Current", Memo: "Monthly allowance in from joint ac", Transfer: true},
+				{Date: "28/11'2011", Amount: "800.00", Number: "", Cleared: "X", Payee: "Us", Label: "Joint - smile Current", Memo: "Monthly allowance in from joint ac", Transfer: true, Line: 1},
 			},
 			wantErrs: []bool{false},
 			wantEOF:  true,
@@ -252,11 +285,71 @@ L[Paul - smile Current]
 ^
 `,
 			wantRecs: []*Record{
-				{Date: "28/11'2011", Amount: "-800.00", Number: "", Cleared: "X", Payee: "Paul", Label: "Paul - smile Current", Memo: "Monthly allowance out to paul ac", Transfer: true},
+				{Date: "28/11'2011", Amount: "-800.00", Number: "", Cleared: "X", Payee: "Paul", Label: "Paul - smile Current", Memo: "Monthly allowance out to paul ac", Transfer: true, Line: 1},
 			},
 			wantErrs: []bool{false},
 			wantEOF:  true,
 		},
+		{
+			desc: "investment records",
+			qif: `!Type:Invst
+D15/03'2003
+NBuy
+YVanguard FTSE All-World
+I50.00
+Q10
+O5.00
+T505.00
+CX
+^
+D16/03'2003
+NXIn
+T100.00
+L[Paul - smile Current]
+$100.00
+^
+`,
+			wantRecs: []*Record{
+				{Type: "Type:Invst", Date: "15/03'2003", Action: "Buy", Security: "Vanguard FTSE All-World", Price: "50.00", Quantity: "10", Commission: "5.00", Amount: "505.00", Cleared: "X", Line: 1},
+				{Date: "16/03'2003", Action: "XIn", Amount: "100.00", Label: "Paul - smile Current", Transfer: true, TransferAmount: "100.00", Line: 11, Index: 1},
+			},
+			wantErrs: []bool{false, false},
+			wantEOF:  true,
+		},
+		{
+			desc: "investment fields ignored in bank records",
+			qif: `!Type:Bank
+D15/03'2003
+N123
+YVanguard FTSE All-World
+^
+`,
+			wantRecs: []*Record{{Type: "Type:Bank", Date: "15/03'2003", Number: "123", Line: 1}},
+			wantErrs: []bool{false},
+			wantEOF:  true,
+		},
+		{
+			desc: "split field before any S line",
+			qif: `D15/03'2003
+$-10.00
+^
+D16/03'2003
+^
+`,
+			wantRecs: []*Record{nil, {Date: "16/03'2003", Line: 4, Index: 1}},
+			wantErrs: []bool{true, false},
+			wantEOF:  true,
+		},
+		{
+			desc: "empty line",
+			qif: `D15/03'2003
+
+^
+`,
+			wantRecs: []*Record{nil, {Line: 3}},
+			wantErrs: []bool{true, false},
+			wantEOF:  true,
+		},
 	}
 
 	for _, test := range tests {
@@ -285,8 +378,57 @@ L[Paul - smile Current]
 	}
 }
 
+func TestNext_lenient(t *testing.T) {
+	qif := "!Type:Bank  \r\n" +
+		"D15/03'2003\r\n" +
+		"\r\n" +
+		"  T-10.00\r\n" +
+		"EOrphaned memo\r\n" +
+		"$-10.00\r\n" +
+		"Zunknown\r\n" +
+		"LFood  \r\n" +
+		"^\r\n" +
+		"   \r\n" +
+		"D16/03'2003\r\n" +
+		"^\r\n"
+	want := []*Record{
+		{Type: "Type:Bank", Date: "15/03'2003", Amount: "-10.00", Label: "Food", Splits: []*Split{{Memo: "Orphaned memo", Amount: "-10.00"}}, Line: 1},
+		{Date: "16/03'2003", Line: 11, Index: 1},
+	}
+	wantWarnings := []Warning{
+		{File: "bank.qif", Line: 1, Record: 0, Field: "!", Msg: "whitespace after field value ignored"},
+		{File: "bank.qif", Line: 3, Record: 0, Msg: "blank line ignored"},
+		{File: "bank.qif", Line: 4, Record: 0, Field: "T", Msg: "whitespace before field code ignored"},
+		{File: "bank.qif", Line: 5, Record: 0, Field: "E", Msg: "split field before any S line; split with no category started"},
+		{File: "bank.qif", Line: 7, Record: 0, Field: "Z", Msg: "unknown field ignored"},
+		{File: "bank.qif", Line: 8, Record: 0, Field: "L", Msg: "whitespace after field value ignored"},
+		{File: "bank.qif", Line: 10, Record: 1, Msg: "blank line ignored"},
+	}
+	q := NewWithOptions(strings.NewReader(qif), decoder, Options{Name: "bank.qif", Lenient: true})
+	var got []*Record
+	for {
+		r, err := q.Next()
+		if err == ErrEOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		got = append(got, r)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Next() records=%v want %v", got, want)
+	}
+	if !reflect.DeepEqual(q.Warnings(), wantWarnings) {
+		t.Errorf("Warnings()=%+v want %+v", q.Warnings(), wantWarnings)
+	}
+	if got, want := wantWarnings[4].String(), `bank.qif: line 7, record 0, field "Z": unknown field ignored`; got != want {
+		t.Errorf("Warning.String()=%q want %q", got, want)
+	}
+}
+
 func TestSanitizeLabel(t *testing.T) {
-	for _, tc := range []struct{
+	for _, tc := range []struct {
 		in, wantOut  string
 		wantTransfer bool
 	}{
@@ -307,8 +449,28 @@ func TestSanitizeLabel(t *testing.T) {
 	}
 }
 
+func TestSplitClass(t *testing.T) {
+	for _, tc := range []struct {
+		in, wantCategory, wantClass string
+	}{
+		{"", "", ""},
+		{"Food", "Food", ""},
+		{"Food:Groceries/Holiday2019", "Food:Groceries", "Holiday2019"},
+		{"Travel/Holiday:France", "Travel", "Holiday:France"},
+		{"[Savings]/Holiday", "[Savings]", "Holiday"},
+		{"/Work", "", "Work"},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			category, class := splitClass(tc.in)
+			if category != tc.wantCategory || class != tc.wantClass {
+				t.Errorf("splitClass(%s)=%q,%q want %q,%q", tc.in, category, class, tc.wantCategory, tc.wantClass)
+			}
+		})
+	}
+}
+
 func TestParseDate(t *testing.T) {
-	for _, tc := range []struct{
+	for _, tc := range []struct {
 		in       string
 		wantTime time.Time
 		wantErr  bool
@@ -0,0 +1,252 @@
+package qif
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/text/encoding"
+)
+
+// DateStyle selects the format in which a Writer writes dates.
+type DateStyle int
+
+// Date styles supported by Writer.
+const (
+	OriginalDates DateStyle = iota // Dates are written exactly as they were read.
+	MoneyDates                     // dd/mm'yyyy, or dd/mm/yyyy before 2000, as written by Microsoft Money.
+	USDates                        // mm/dd'yyyy, or mm/dd/yyyy before 2000, as written by US versions of Quicken.
+	ISODates                       // yyyy-mm-dd.
+)
+
+// Writer writes records in QIF format.
+type Writer struct {
+	w       io.Writer
+	encoder *encoding.Encoder
+	dialect DateDialect // The dialect dates are read in.
+	dates   DateStyle
+	section string // The most recent "Account" or "Type:..." header written.
+	err     error  // The first error writing a line.
+}
+
+// NewWriter returns a Writer that writes QIF data to w.  Character set
+// conversion from UTF-8 is performed by enc, or not at all if enc is nil.
+// Dates, read in the dialect dd unless they're written as they were read, are
+// written in the given style.
+func NewWriter(w io.Writer, enc *encoding.Encoder, dd DateDialect, dates DateStyle) *Writer {
+	return &Writer{w: w, encoder: enc, dialect: dd, dates: dates}
+}
+
+// WriteFile writes the class and category lists of f, followed by each of its
+// accounts.
+func (w *Writer) WriteFile(f *File) error {
+	if len(f.Classes) > 0 {
+		w.header("Type:Class")
+		for _, c := range f.Classes {
+			w.field("N", c.Name)
+			w.field("D", c.Description)
+			w.line("^")
+		}
+	}
+	if len(f.Categories) > 0 {
+		w.header("Type:Cat")
+		for _, c := range f.Categories {
+			w.category(c)
+		}
+	}
+	for _, rs := range f.Accounts {
+		if err := w.WriteRecordSet(rs); err != nil {
+			return err
+		}
+	}
+	return w.err
+}
+
+// WriteRecordSet writes the account header of rs, if it has one, followed by
+// its opening record and its records.  An opening record supplied by ReadFile
+// for an account with no opening balance record is not written.  rs must have
+// an opening record, which gives the type of its records.
+func (w *Writer) WriteRecordSet(rs *RecordSet) error {
+	if rs.Opening == nil {
+		return fmt.Errorf("QIF: RecordSet has no Opening record")
+	}
+	if rs.Account != nil {
+		w.account(rs.Account)
+	}
+	if rs.Opening.Date != "" || rs.Opening.Amount != "" {
+		if err := w.Write(rs.Opening, rs.Opening.Type); err != nil {
+			return err
+		}
+	}
+	for _, r := range rs.Records {
+		if err := w.Write(r, rs.Opening.Type); err != nil {
+			return err
+		}
+	}
+	return w.err
+}
+
+// Write writes a single record, of an account of the given type, eg.
+// "Type:Bank".  The record is preceded by its own !Type: header if it has one,
+// or else by a header for the type unless the previous record was of that type.
+func (w *Writer) Write(r *Record, typ string) error {
+	switch {
+	case r.Type != "":
+		w.header(r.Type)
+	case w.section != typ:
+		w.header(typ)
+	}
+	if w.section == "Type:Invst" {
+		w.investment(r)
+	} else {
+		w.record(r)
+	}
+	w.line("^")
+	return w.err
+}
+
+// account writes an !Account header.
+func (w *Writer) account(a *Account) {
+	w.header("Account")
+	w.field("N", a.Name)
+	w.field("T", a.Type)
+	w.field("D", a.Description)
+	w.field("L", a.CreditLimit)
+	w.date("/", a.BalanceDate)
+	w.field("$", a.Balance)
+	w.line("^")
+}
+
+// category writes an entry of a category list.
+func (w *Writer) category(c *Category) {
+	w.field("N", c.Name)
+	w.field("D", c.Description)
+	for _, f := range []struct {
+		spec string
+		set  bool
+	}{
+		{"I", c.Income},
+		{"E", c.Expense},
+		{"T", c.Tax},
+	} {
+		if f.set {
+			w.line(f.spec)
+		}
+	}
+	w.field("R", c.TaxSchedule)
+	w.field("B", c.Budget)
+	w.line("^")
+}
+
+// record writes the fields of a non-investment record.
+func (w *Writer) record(r *Record) {
+	w.date("D", r.Date)
+	w.field("T", r.Amount)
+	w.field("C", r.Cleared)
+	w.field("N", r.Number)
+	w.field("P", r.Payee)
+	w.field("M", r.Memo)
+	w.label(r)
+	for _, s := range r.Splits {
+		category := s.Category
+		if s.Transfer {
+			category = "[" + category + "]"
+		}
+		w.line("S" + withClass(category, s.Class))
+		w.field("E", s.Memo)
+		w.field("$", s.Amount)
+		w.field("%", s.Percent)
+	}
+}
+
+// investment writes the fields of an investment record.
+func (w *Writer) investment(r *Record) {
+	w.date("D", r.Date)
+	w.field("N", r.Action)
+	w.field("Y", r.Security)
+	w.field("I", r.Price)
+	w.field("Q", r.Quantity)
+	w.field("T", r.Amount)
+	w.field("C", r.Cleared)
+	w.field("P", r.Payee)
+	w.field("M", r.Memo)
+	w.field("O", r.Commission)
+	w.label(r)
+	w.field("$", r.TransferAmount)
+}
+
+// label writes the L line of r, wrapping the name of a transfer account in [ ]
+// and following it with any class.
+func (w *Writer) label(r *Record) {
+	if r.Transfer {
+		w.line("L" + withClass("["+r.Label+"]", r.Class))
+		return
+	}
+	w.field("L", withClass(r.Label, r.Class))
+}
+
+// withClass returns the category c followed by the class, if any.
+func withClass(c, class string) string {
+	if class == "" {
+		return c
+	}
+	return c + "/" + class
+}
+
+// header writes a ! line starting a new section.
+func (w *Writer) header(section string) {
+	w.line("!" + section)
+	w.section = section
+}
+
+// date writes a date line in the Writer's date style, if d is set.
+func (w *Writer) date(spec, d string) {
+	if d == "" || w.dates == OriginalDates {
+		w.field(spec, d)
+		return
+	}
+	t, err := w.dialect.Parse(d)
+	if err != nil {
+		if w.err == nil {
+			w.err = fmt.Errorf("QIF: writing date %q: %v", d, err)
+		}
+		return
+	}
+	switch w.dates {
+	case MoneyDates:
+		d = FormatDate(t)
+	case USDates:
+		layout := "01/02'2006"
+		if t.Year() < 2000 {
+			layout = "01/02/2006"
+		}
+		d = t.Format(layout)
+	default:
+		d = t.Format("2006-01-02")
+	}
+	w.field(spec, d)
+}
+
+// field writes a field line, if value is set.
+func (w *Writer) field(spec, value string) {
+	if value != "" {
+		w.line(spec + value)
+	}
+}
+
+// line writes a single line, unless an earlier line failed.
+func (w *Writer) line(l string) {
+	if w.err != nil {
+		return
+	}
+	if w.encoder != nil {
+		encoded, err := w.encoder.String(l)
+		if err != nil {
+			w.err = fmt.Errorf("QIF: encoding.Encoder.String(%q): err %v", l, err)
+			return
+		}
+		l = encoded
+	}
+	if _, err := io.WriteString(w.w, l+"\n"); err != nil {
+		w.err = err
+	}
+}
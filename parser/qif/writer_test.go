@@ -0,0 +1,154 @@
+package qif
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+var encoder = charmap.ISO8859_15.NewEncoder()
+
+func TestWriter_roundTrip(t *testing.T) {
+	tests := []struct {
+		desc string
+		qif  string
+	}{
+		{
+			desc: "bank account with splits",
+			qif: "!Type:Bank\n" +
+				"D31/12/1999\nT1,100.00\nCX\nPOpening Balance\nL[Current]\n^\n" +
+				"D02/01'2016\nT-14.40\nN123\nPCafé\nMLunch\nLFood\nSFood:Groceries\nEBread\n$-10.00\nSFood\n%25\nS[Savings]\n$-1.00\n^\n" +
+				"D03/01'2016\nT-40.00\nL[Savings]\n^\n",
+		},
+		{
+			desc: "classes",
+			qif: "!Type:Bank\n" +
+				"D02/01'2016\nT-14.40\nLFood/Holiday2019\nSFood:Groceries/Holiday2019:France\n$-10.00\nS/Work\n$-4.40\n^\n" +
+				"D03/01'2016\nT-40.00\nL[Savings]/Holiday2019\n^\n",
+		},
+		{
+			desc: "accounts, lists and investments",
+			qif: "!Type:Class\nNHoliday2019\nDSummer holiday\n^\n" +
+				"!Type:Cat\nNFood\nDFood and drink\nE\nB200.00\n^\nNSalary\nI\nT\nRW-2\n^\n" +
+				"!Account\nNVISA\nTCCard\nL2,500.00\n/31/01'2016\n$-123.45\n^\n" +
+				"!Type:CCard\nD03/01'2016\nT-20.00\nPGarage\n^\n" +
+				"!Account\nNShares\nTInvst\n^\n" +
+				"!Type:Invst\nD15/03'2003\nNBuy\nYVanguard FTSE All-World\nI50.00\nQ10\nT505.00\nCX\nO5.00\n^\n" +
+				"D16/03'2003\nNXIn\nT1000.00\nL[Current]\n$1000.00\n^\n" +
+				"!Account\nNSavings\nTBank\n^\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			want, err := ReadFile(strings.NewReader(test.qif), decoder)
+			if err != nil {
+				t.Fatalf("ReadFile() error: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := NewWriter(&buf, encoder, DateDialect{}, OriginalDates).WriteFile(want); err != nil {
+				t.Fatalf("WriteFile() error: %v", err)
+			}
+			if got := buf.String(); got != test.qif {
+				t.Errorf("WriteFile() wrote:\n%s\nwant:\n%s", got, test.qif)
+			}
+			got, err := ReadFile(&buf, decoder)
+			if err != nil {
+				t.Fatalf("ReadFile() of written QIF error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ReadFile() of written QIF=%+v want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestWriter_dates(t *testing.T) {
+	tests := []struct {
+		dates   DateStyle
+		date    string
+		want    string
+		wantErr bool
+	}{
+		{dates: OriginalDates, date: "2/1'16", want: "D2/1'16"},
+		{dates: MoneyDates, date: "02/01/2016", want: "D02/01'2016"},
+		{dates: MoneyDates, date: "31/12'1999", want: "D31/12/1999"},
+		{dates: USDates, date: "02/01'2016", want: "D01/02'2016"},
+		{dates: USDates, date: "31/12'1999", want: "D12/31/1999"},
+		{dates: ISODates, date: "02/01'2016", want: "D2016-01-02"},
+		{dates: ISODates, date: "2016-01-02", want: "D2016-01-02"},
+		{dates: ISODates, date: "31/02'2016", wantErr: true},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		err := NewWriter(&buf, nil, DateDialect{}, test.dates).Write(&Record{Date: test.date}, "Type:Bank")
+		if gotErr := err != nil; gotErr != test.wantErr {
+			t.Errorf("Write(%q) style %d err? %t want? %t (err=%v)", test.date, test.dates, gotErr, test.wantErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got, want := buf.String(), "!Type:Bank\n"+test.want+"\n^\n"; got != want {
+			t.Errorf("Write(%q) style %d wrote %q want %q", test.date, test.dates, got, want)
+		}
+	}
+}
+
+func TestWriter_monthDayDates(t *testing.T) {
+	in := "!Account\nNVISA\nTCCard\n/1/31'17\n$-20.00\n^\n" +
+		"!Type:CCard\nD12/31'2016\nT-5.00\n^\nD1/2'2017\nT-15.00\n^\n"
+	f, err := ReadFile(strings.NewReader(in), decoder)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	tests := []struct {
+		dates DateStyle
+		want  string
+	}{
+		{dates: USDates, want: "!Account\nNVISA\nTCCard\n/01/31'2017\n$-20.00\n^\n" +
+			"!Type:CCard\nD12/31'2016\nT-5.00\n^\nD01/02'2017\nT-15.00\n^\n"},
+		{dates: MoneyDates, want: "!Account\nNVISA\nTCCard\n/31/01'2017\n$-20.00\n^\n" +
+			"!Type:CCard\nD31/12'2016\nT-5.00\n^\nD02/01'2017\nT-15.00\n^\n"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := NewWriter(&buf, nil, DateDialect{Order: MonthDay}, test.dates).WriteFile(f); err != nil {
+			t.Errorf("WriteFile() style %d error: %v", test.dates, err)
+			continue
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("WriteFile() style %d wrote:\n%s\nwant:\n%s", test.dates, got, test.want)
+		}
+	}
+}
+
+func TestWriter_encoding(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf, encoder, DateDialect{}, OriginalDates).Write(&Record{Payee: "Café €"}, "Type:Cash"); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if got, want := buf.String(), "!Type:Cash\nPCaf\xe9 \xa4\n^\n"; got != want {
+		t.Errorf("Write() wrote %q want %q", got, want)
+	}
+}
+
+func TestWriter_encodingError(t *testing.T) {
+	var buf bytes.Buffer
+	err := NewWriter(&buf, encoder, DateDialect{}, OriginalDates).Write(&Record{Payee: "Café 中"}, "Type:Cash")
+	if err == nil {
+		t.Fatalf("Write() of an unencodable payee got no error")
+	}
+	if want := `"PCafé 中"`; !strings.Contains(err.Error(), want) {
+		t.Errorf("Write() error %q doesn't give the line %s", err, want)
+	}
+}
+
+func TestWriter_noOpening(t *testing.T) {
+	rs := &RecordSet{Account: &Account{Name: "Current", Type: "Bank"}}
+	if err := NewWriter(&bytes.Buffer{}, nil, DateDialect{}, OriginalDates).WriteRecordSet(rs); err == nil {
+		t.Errorf("WriteRecordSet() of a RecordSet with no Opening got no error")
+	}
+}